@@ -4,9 +4,13 @@ import (
 	"backend/config"
 	"backend/models"
 	"backend/repositories"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
@@ -28,78 +32,197 @@ type StockApi struct {
 	RatingFrom string `json:"rating_from"`
 	RatingTo   string `json:"rating_to"`
 	Time       string `json:"time"`
+
+	Raw json.RawMessage `json:"-"` // the item exactly as the upstream sent it
 }
 
-func FetchData() (string, error) {
-	baseURl := config.LoadApi().URL
-	token := config.LoadApi().Token
-	var batch []models.Stock
-	nextPage := ""
-	resp := ""
-	for {
-		url := baseURl
-		if nextPage != "" {
-			url = fmt.Sprintf("%s?next_page=%s", url, nextPage)
-		}
-		items, newNextPage, err := FetchPage(url, token)
+func (s *StockApi) UnmarshalJSON(data []byte) error {
+	type plain StockApi
+	var item plain
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	*s = StockApi(item)
+	s.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type SyncOptions struct {
+	JobID   uint
+	Restart bool
+	OnEvent func(SyncEvent)
+	Sources []Source // defaults to the sources selected in config.LoadSources
+}
+
+type SyncStats struct {
+	Pages    int         `json:"pages"`
+	Inserted int         `json:"inserted"`
+	Updated  int         `json:"updated"`
+	Ignored  int         `json:"ignored"`
+	Rejected int         `json:"rejected"`
+	Failed   int         `json:"failed"`
+	Timings  SyncTimings `json:"timings"`
+}
+
+// FetchData crawls every source page by page and stores the ratings in
+// repos. After
+// every stored batch the cursor of the next page is saved as the source's
+// checkpoint, so an interrupted crawl resumes from there unless opts.Restart
+// is set. Items that fail to convert are quarantined. opts.OnEvent, if not
+// nil, is called for every page fetched, batch stored and rows rejected, from
+// the stages concurrently.
+// Cancelling ctx stops fetching, the pages already fetched are still stored
+// and checkpointed.
+func FetchData(ctx context.Context, repos *repositories.Repositories, opts SyncOptions) (SyncStats, error) {
+	var stats SyncStats
+
+	// checked before any stage starts, nothing is fetched for a sync that
+	// can't store what it fetches
+	cfg := config.LoadPipeline()
+	policy, err := repositories.ParseConflictPolicy(cfg.ConflictPolicy)
+	if err != nil {
+		return stats, err
+	}
+
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources, err = LoadSources()
 		if err != nil {
-			return "", fmt.Errorf("Can't get page: Error %v", err)
+			return stats, fmt.Errorf("Can't load sources: Error %v", err)
 		}
+	}
 
-		for _, item := range items {
-			//fmt.Println("this is the data :", item)
-			stock, err := ConvertStockApi(item)
-			if err == nil {
-				batch = append(batch, stock)
-			}
+	for _, source := range sources {
+		if err := crawl(ctx, repos, source, opts, cfg, policy, &stats); err != nil {
+			return stats, fmt.Errorf("source %s: %w", source.Name(), err)
 		}
+	}
+	return stats, nil
+}
 
+// FetchPage requests a single page, retrying transient failures with the
+// policy configured through config.LoadRetry.
+func FetchPage(url, token string) ([]StockApi, string, error) {
+	retry := config.LoadRetry()
+	client := &http.Client{Timeout: retry.Timeout}
+	return FetchPageWithRetry(context.Background(), client, url, token, retry)
+}
 
-		for len(batch) >= 100 {
-			resp, err = repositories.StoreStock(batch)
-			batch = batch[len(batch):]
+func FetchPageWithRetry(ctx context.Context, client *http.Client, url, token string, retry config.RetryConfig) ([]StockApi, string, error) {
+	attempts := retry.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		var data ResponeStruct
+		data, err = fetchPageOnce(ctx, client, url, token)
+		if err == nil {
+			return data.Items, data.NextPage, nil
+		}
+		if !IsTransient(err) || ctx.Err() != nil {
+			return nil, "", err
+		}
+		if attempt == attempts-1 {
+			break
 		}
 
-		if newNextPage == "" {
-			for len(batch) > 0 {
-				repositories.StoreStock(batch)
-				batch = batch[len(batch):]
-				fmt.Println("this is the final batch :", batch)
+		delay := backoff(retry, attempt)
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			// honoured up to MaxDelay, a day long Retry-After would hold the
+			// sync for a day
+			delay = statusErr.RetryAfter
+			if retry.MaxDelay > 0 && delay > retry.MaxDelay {
+				delay = retry.MaxDelay
 			}
-			break
 		}
-		fmt.Println("this is the next page :", newNextPage)
-		nextPage = newNextPage
+		log.Printf("fetching %s failed (attempt %d/%d), retrying in %v: %v", url, attempt+1, attempts, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, "", ctx.Err()
+		case <-timer.C:
+		}
 	}
 
-	return resp, nil
+	return nil, "", fmt.Errorf("giving up after %d attempts: %w", attempts, err)
 }
 
-func FetchPage(url, token string) ([]StockApi, string, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func fetchPageOnce(ctx context.Context, client *http.Client, url, token string) (ResponeStruct, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		fmt.Errorf("fail Request: %v", err)
+		return ResponeStruct{}, fmt.Errorf("fail Request: %w", err)
 	}
 
 	req.Header.Add("Authorization", token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Errorf("fail doing request: %v", err)
+		return ResponeStruct{}, &TransportError{URL: url, Err: err}
 	}
 
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Errorf("fail reading the response: %v", err)
+		return ResponeStruct{}, &TransportError{URL: url, Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ResponeStruct{}, &HTTPStatusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Body:       truncate(string(body), 200),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
 	var data ResponeStruct
 	if err := json.Unmarshal(body, &data); err != nil {
-		fmt.Errorf("fail reading the body: %v", err)
+		return ResponeStruct{}, &DecodeError{URL: url, Err: err}
 	}
-	return data.Items, data.NextPage, nil
+	return data, nil
+}
+
+// backoff is the exponential delay for the given attempt with full jitter
+// over its upper half, so concurrent clients don't retry in lockstep.
+func backoff(retry config.RetryConfig, attempt int) time.Duration {
+	delay := retry.BaseDelay << attempt
+	if delay <= 0 || (retry.MaxDelay > 0 && delay > retry.MaxDelay) {
+		delay = retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(delay-half)+1))
+}
+
+// parseRetryAfter accepts both forms of the header: delay-seconds and HTTP-date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max] + "..."
 }
 
 func ConvertStockApi(stocktoConvert StockApi) (models.Stock, error) {
@@ -118,7 +241,17 @@ func ConvertStockApi(stocktoConvert StockApi) (models.Stock, error) {
 	time, err3 := time.Parse(time.RFC3339, stocktoConvert.Time)
 
 	if err1 != nil || err2 != nil || err3 != nil {
-		return models.Stock{}, fmt.Errorf("invalid data in API response %v %v %v", err1, err2, err3)
+		var invalid []string
+		if err1 != nil {
+			invalid = append(invalid, fmt.Sprintf("target_from: %v", err1))
+		}
+		if err2 != nil {
+			invalid = append(invalid, fmt.Sprintf("target_to: %v", err2))
+		}
+		if err3 != nil {
+			invalid = append(invalid, fmt.Sprintf("time: %v", err3))
+		}
+		return models.Stock{}, fmt.Errorf("invalid data in API response: %s", strings.Join(invalid, "; "))
 	}
 
 	return models.Stock{
@@ -133,3 +266,18 @@ func ConvertStockApi(stocktoConvert StockApi) (models.Stock, error) {
 		Time:       time,
 	}, nil
 }
+
+// Quarantine builds the quarantine row for an item ConvertStockApi rejected.
+func Quarantine(item StockApi, convertErr error, source, cursor string, jobID uint) models.QuarantinedStock {
+	payload := item.Raw
+	if len(payload) == 0 {
+		payload, _ = json.Marshal(item)
+	}
+	return models.QuarantinedStock{
+		Payload: string(payload),
+		Error:   convertErr.Error(),
+		Source:  source,
+		Cursor:  cursor,
+		JobID:   jobID,
+	}
+}
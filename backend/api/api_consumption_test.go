@@ -0,0 +1,220 @@
+package api
+
+import (
+	"backend/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetry = config.RetryConfig{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    10 * time.Millisecond,
+	Timeout:     time.Second,
+}
+
+const testPage = `{"items":[{"ticker":"AAPL","target_from":"$1.00","target_to":"$2.00","time":"2025-01-01T00:00:00Z"}],"next_page":"AAPL"}`
+
+// upstream answers the requests with responses in order, the last one
+// repeating, and counts them.
+func upstream(t *testing.T, responses ...func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		responses[min(n, len(responses))-1](w)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+		fmt.Fprint(w, `{"error":"nope"}`)
+	}
+}
+
+func body(payload string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		fmt.Fprint(w, payload)
+	}
+}
+
+func TestFetchPageWithRetryRetriesTransientErrors(t *testing.T) {
+	server, requests := upstream(t, status(500), status(503), body(testPage))
+
+	items, next, err := FetchPageWithRetry(context.Background(), server.Client(), server.URL, "token", testRetry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Ticker != "AAPL" || next != "AAPL" {
+		t.Errorf("got %d items and next %q, want the page", len(items), next)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+}
+
+func TestFetchPageWithRetryGivesUp(t *testing.T) {
+	server, requests := upstream(t, status(502))
+
+	_, _, err := FetchPageWithRetry(context.Background(), server.Client(), server.URL, "token", testRetry)
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 502 {
+		t.Fatalf("got %v, want an HTTPStatusError with status 502", err)
+	}
+	if got := requests.Load(); got != int32(testRetry.MaxAttempts) {
+		t.Errorf("got %d requests, want %d", got, testRetry.MaxAttempts)
+	}
+}
+
+func TestFetchPageWithRetryDoesNotRetryPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response func(w http.ResponseWriter)
+		check    func(err error) bool
+	}{
+		{"not found", status(404), func(err error) bool {
+			var statusErr *HTTPStatusError
+			return errors.As(err, &statusErr) && statusErr.StatusCode == 404
+		}},
+		{"unauthorized", status(401), func(err error) bool {
+			var statusErr *HTTPStatusError
+			return errors.As(err, &statusErr) && statusErr.StatusCode == 401
+		}},
+		{"malformed json", body(`{"items": [`), func(err error) bool {
+			var decodeErr *DecodeError
+			return errors.As(err, &decodeErr)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := upstream(t, test.response)
+
+			_, _, err := FetchPageWithRetry(context.Background(), server.Client(), server.URL, "token", testRetry)
+			if !test.check(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if IsTransient(err) {
+				t.Errorf("%v is transient, want permanent", err)
+			}
+			if got := requests.Load(); got != 1 {
+				t.Errorf("got %d requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestFetchPageWithRetryCapsRetryAfter(t *testing.T) {
+	server, requests := upstream(t, status(429, "Retry-After", "86400"), body(testPage))
+
+	started := time.Now()
+	_, _, err := FetchPageWithRetry(context.Background(), server.Client(), server.URL, "token", testRetry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("took %v, Retry-After should be capped at %v", elapsed, testRetry.MaxDelay)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
+
+func TestFetchPageWithRetryStopsWithContext(t *testing.T) {
+	server, _ := upstream(t, status(500))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := FetchPageWithRetry(ctx, server.Client(), server.URL, "token", testRetry)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestFetchPageWithRetrySendsToken(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		fmt.Fprint(w, testPage)
+	}))
+	defer server.Close()
+
+	if _, _, err := FetchPageWithRetry(context.Background(), server.Client(), server.URL, "secret", testRetry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "secret" {
+		t.Errorf("got Authorization %q, want %q", got, "secret")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retry := config.RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		want := min(retry.BaseDelay<<attempt, retry.MaxDelay)
+		if got := backoff(retry, attempt); got < want/2 || got > want {
+			t.Errorf("attempt %d: got %v, want between %v and %v", attempt, got, want/2, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("got %v, want 3s", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 0 || got > time.Minute {
+		t.Errorf("got %v for %s, want up to a minute", got, date)
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if got := parseRetryAfter(value); got != 0 {
+			t.Errorf("got %v for %q, want 0", got, value)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	// transport wraps cause like http.Client.Do does
+	transport := func(cause error) error {
+		return &TransportError{URL: "http://upstream", Err: &url.Error{Op: "Get", URL: "http://upstream", Err: cause}}
+	}
+	dial := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unknown host", transport(dial(&net.DNSError{Err: "no such host", Name: "upstream", IsNotFound: true})), false},
+		{"dns timeout", transport(dial(&net.DNSError{Err: "i/o timeout", Name: "upstream", IsTimeout: true})), true},
+		{"dns server failure", transport(dial(&net.DNSError{Err: "server misbehaving", Name: "upstream", IsTemporary: true})), true},
+		{"connection refused", transport(dial(errors.New("connection refused"))), true},
+		{"unexpected eof", transport(io.ErrUnexpectedEOF), true},
+		{"too many requests", &HTTPStatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad gateway", &HTTPStatusError{StatusCode: http.StatusBadGateway}, true},
+		{"not found", &HTTPStatusError{StatusCode: http.StatusNotFound}, false},
+		{"malformed json", &DecodeError{Err: errors.New("unexpected end of JSON input")}, false},
+	}
+
+	for _, test := range tests {
+		if got := IsTransient(test.err); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// FormatFromPath guesses the format from the file extension, "" if unknown.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".json":
+		return FormatJSON
+	default:
+		return ""
+	}
+}
+
+// RowFunc receives every row read from a file with its line number. err is
+// set when that row couldn't be decoded, reading carries on with the next one
+// unless RowFunc returns an error.
+type RowFunc func(line int, item StockApi, err error) error
+
+// ReadRows reads a CSV or NDJSON file row by row.
+func ReadRows(r io.Reader, format string, fn RowFunc) error {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(r, fn)
+	case FormatNDJSON:
+		return ReadNDJSON(r, fn)
+	default:
+		return fmt.Errorf("unsupported format %q, expected csv or ndjson", format)
+	}
+}
+
+// Decode reads a whole CSV or NDJSON file, failing on the first bad row.
+func Decode(r io.Reader, format string) ([]StockApi, error) {
+	var items []StockApi
+	err := ReadRows(r, format, func(line int, item StockApi, err error) error {
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// ReadNDJSON reads one upstream item per line, blank lines are skipped.
+func ReadNDJSON(r io.Reader, fn RowFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var item StockApi
+		err := json.Unmarshal([]byte(text), &item)
+		if err != nil {
+			item = StockApi{Raw: json.RawMessage(text)}
+		}
+		if err := fn(line, item, err); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ReadCSV reads a CSV whose header names the upstream json fields (ticker,
+// target_from, target_to, company, action, brokerage, rating_from, rating_to,
+// time) in any order.
+func ReadCSV(r io.Reader, fn RowFunc) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("can't read csv header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["ticker"]; !ok {
+		return fmt.Errorf("csv header has no ticker column")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var item StockApi
+		var line int
+		var parseErr *csv.ParseError
+		switch {
+		case err == nil:
+			line, _ = reader.FieldPos(0)
+			item = stockFromRecord(columns, record)
+		case errors.As(err, &parseErr):
+			line = parseErr.StartLine
+		default:
+			return err
+		}
+		if err := fn(line, item, err); err != nil {
+			return err
+		}
+	}
+}
+
+func stockFromRecord(columns map[string]int, record []string) StockApi {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	return StockApi{
+		Ticker:     field("ticker"),
+		TargetFrom: field("target_from"),
+		TargetTo:   field("target_to"),
+		Company:    field("company"),
+		Action:     field("action"),
+		Brokerage:  field("brokerage"),
+		RatingFrom: field("rating_from"),
+		RatingTo:   field("rating_to"),
+		Time:       field("time"),
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"fmt"
+	"time"
+)
+
+const maxDiffSamples = 50
+
+type RowDiff struct {
+	Ticker string    `json:"ticker"`
+	Time   time.Time `json:"time"`
+	Fields []string  `json:"fields"`
+}
+
+// DiffReport is what a sync would do, without doing it.
+type DiffReport struct {
+	Pages      int       `json:"pages"`
+	New        int       `json:"new"`        // not stored yet, would be inserted
+	Unchanged  int       `json:"unchanged"`  // stored with the same values
+	Changed    int       `json:"changed"`    // stored under the same (ticker, time) with other values
+	Duplicates int       `json:"duplicates"` // repeated within the crawl itself
+	Rejected   int       `json:"rejected"`   // would be quarantined by ConvertStockApi
+	Samples    []RowDiff `json:"changed_samples"`
+}
+
+// DryRun crawls every source from the first page and compares what it gets
+// against stocks by (ticker, time). Nothing is written: no stocks, no
+// checkpoints, no quarantine rows.
+func DryRun(ctx context.Context, stocks repositories.StockRepository, sources []Source) (DiffReport, error) {
+	report := DiffReport{Samples: []RowDiff{}}
+
+	if len(sources) == 0 {
+		var err error
+		sources, err = LoadSources()
+		if err != nil {
+			return report, fmt.Errorf("Can't load sources: Error %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, source := range sources {
+		err := Each(ctx, source, "", func(items []StockApi, cursor, nextCursor string) error {
+			report.Pages++
+
+			var converted []models.Stock
+			for _, item := range items {
+				stock, err := ConvertStockApi(item)
+				if err != nil {
+					report.Rejected++
+					continue
+				}
+				if seen[stock.Key()] {
+					report.Duplicates++
+					continue
+				}
+				seen[stock.Key()] = true
+				converted = append(converted, stock)
+			}
+
+			existing, err := stocks.GetExisting(ctx, converted)
+			if err != nil {
+				return fmt.Errorf("Can't compare page: Error %v", err)
+			}
+			stored := make(map[string]models.Stock, len(existing))
+			for _, stock := range existing {
+				stored[stock.Key()] = stock
+			}
+
+			for _, stock := range converted {
+				current, ok := stored[stock.Key()]
+				if !ok {
+					report.New++
+					continue
+				}
+				fields := current.Diff(stock)
+				if len(fields) == 0 {
+					report.Unchanged++
+					continue
+				}
+				report.Changed++
+				if len(report.Samples) < maxDiffSamples {
+					report.Samples = append(report.Samples, RowDiff{Ticker: stock.Ticker, Time: stock.Time, Fields: fields})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return report, fmt.Errorf("source %s: %w", source.Name(), err)
+		}
+	}
+	return report, nil
+}
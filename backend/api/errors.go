@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TransportError means the request never got a response: DNS, connection
+// resets, timeouts or a body that couldn't be read.
+type TransportError struct {
+	URL string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("transport error requesting %s: %v", e.URL, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError means the upstream answered with something other than 200.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d from %s: %s", e.StatusCode, e.URL, e.Body)
+}
+
+// DecodeError means the upstream answered 200 but the body isn't a page.
+type DecodeError struct {
+	URL string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("can't decode page from %s: %v", e.URL, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient reports whether retrying the same request may succeed.
+func IsTransient(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		cause := transportErr.Err
+		// every error from http.Client.Do is a *url.Error, look at what it wraps
+		var urlErr *url.Error
+		if errors.As(cause, &urlErr) {
+			if urlErr.Timeout() {
+				return true
+			}
+			cause = urlErr.Err
+		}
+		// a host that doesn't exist won't after a retry either
+		var dnsErr *net.DNSError
+		if errors.As(cause, &dnsErr) && dnsErr.IsNotFound {
+			return false
+		}
+		var netErr net.Error
+		return errors.As(cause, &netErr) || errors.Is(cause, io.EOF) || errors.Is(cause, io.ErrUnexpectedEOF)
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package api
+
+import "time"
+
+const (
+	EventPageFetched  = "page_fetched"
+	EventBatchStored  = "batch_stored"
+	EventRowsRejected = "rows_rejected"
+	EventCompleted    = "completed"
+	EventFailed       = "failed"
+)
+
+// SyncEvent reports progress of a crawl. Totals always carries the running
+// totals so a client joining late can render progress from any event.
+type SyncEvent struct {
+	Type     string    `json:"type"`
+	JobID    uint      `json:"job_id,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	Page     int       `json:"page,omitempty"`
+	Cursor   string    `json:"cursor,omitempty"`
+	Items    int       `json:"items,omitempty"`
+	Inserted int       `json:"inserted,omitempty"`
+	Updated  int       `json:"updated,omitempty"`
+	Ignored  int       `json:"ignored,omitempty"`
+	Rejected int       `json:"rejected,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Totals   SyncStats `json:"totals"`
+	Time     time.Time `json:"time"`
+}
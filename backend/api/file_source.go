@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const filePageSize = 100
+
+// FileSource reads ratings from a local .json, .ndjson/.jsonl or .csv file
+// with the same fields as the upstream. The cursor is the offset of the
+// next item in the file.
+type FileSource struct {
+	name string
+	path string
+
+	once  sync.Once
+	items []StockApi
+	err   error
+}
+
+func NewFileSource(name, path string) *FileSource {
+	return &FileSource{name: name, path: path}
+}
+
+func (s *FileSource) Name() string {
+	return s.name
+}
+
+func (s *FileSource) FetchPage(ctx context.Context, cursor string) ([]StockApi, string, error) {
+	s.once.Do(func() {
+		s.items, s.err = readRatingsFile(s.path)
+	})
+	if s.err != nil {
+		return nil, "", s.err
+	}
+
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 || offset > len(s.items) {
+			return nil, "", fmt.Errorf("invalid cursor %q for file source %s", cursor, s.name)
+		}
+	}
+
+	end := min(offset+filePageSize, len(s.items))
+	next := ""
+	if end < len(s.items) {
+		next = strconv.Itoa(end)
+	}
+	return s.items[offset:end], next, nil
+}
+
+func readRatingsFile(path string) ([]StockApi, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	switch format := FormatFromPath(path); format {
+	case FormatJSON:
+		return decodeJSON(file)
+	case "":
+		return nil, fmt.Errorf("unsupported file type %s", path)
+	default:
+		return Decode(file, format)
+	}
+}
+
+// decodeJSON accepts an upstream page ({"items": [...]}) or a bare array.
+func decodeJSON(r io.Reader) ([]StockApi, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []StockApi
+	if err := json.Unmarshal(body, &items); err == nil {
+		return items, nil
+	}
+
+	var page ResponeStruct
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("can't decode json: %v", err)
+	}
+	return page.Items, nil
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// FixtureSource serves fixed pages from memory, the cursor is the page index.
+type FixtureSource struct {
+	name  string
+	pages [][]StockApi
+}
+
+func NewFixtureSource(name string, pages ...[]StockApi) *FixtureSource {
+	return &FixtureSource{name: name, pages: pages}
+}
+
+func (s *FixtureSource) Name() string {
+	return s.name
+}
+
+func (s *FixtureSource) FetchPage(ctx context.Context, cursor string) ([]StockApi, string, error) {
+	index := 0
+	if cursor != "" {
+		var err error
+		index, err = strconv.Atoi(cursor)
+		if err != nil || index < 0 || index >= len(s.pages) {
+			return nil, "", fmt.Errorf("invalid cursor %q for fixture source %s", cursor, s.name)
+		}
+	}
+	if len(s.pages) == 0 {
+		return nil, "", nil
+	}
+
+	next := ""
+	if index+1 < len(s.pages) {
+		next = strconv.Itoa(index + 1)
+	}
+	return s.pages[index], next, nil
+}
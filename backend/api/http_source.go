@@ -0,0 +1,41 @@
+package api
+
+import (
+	"backend/config"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// HTTPSource is the paginated {items, next_page} API the app was built for.
+type HTTPSource struct {
+	name   string
+	url    string
+	token  string
+	client *http.Client
+	retry  config.RetryConfig
+}
+
+func NewHTTPSource(name, url, token string) *HTTPSource {
+	retry := config.LoadRetry()
+	return &HTTPSource{
+		name:   name,
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: retry.Timeout},
+		retry:  retry,
+	}
+}
+
+func (s *HTTPSource) Name() string {
+	return s.name
+}
+
+func (s *HTTPSource) FetchPage(ctx context.Context, cursor string) ([]StockApi, string, error) {
+	pageURL := s.url
+	if cursor != "" {
+		pageURL = fmt.Sprintf("%s?next_page=%s", pageURL, url.QueryEscape(cursor))
+	}
+	return FetchPageWithRetry(ctx, s.client, pageURL, s.token, s.retry)
+}
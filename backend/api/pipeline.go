@@ -0,0 +1,316 @@
+package api
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// SyncTimings shows where a sync spends its time. Writers run concurrently so
+// WriteMs can exceed the wall time of the sync.
+type SyncTimings struct {
+	FetchMs   int64 `json:"fetch_ms"`   // waiting on the source
+	ConvertMs int64 `json:"convert_ms"` // converting and quarantining rows
+	WriteMs   int64 `json:"write_ms"`   // storing batches, summed over writers
+	StalledMs int64 `json:"stalled_ms"` // fetcher blocked because the buffer was full
+}
+
+type fetchedPage struct {
+	items      []StockApi
+	cursor     string
+	nextCursor string
+}
+
+type writeBatch struct {
+	index      int
+	stocks     []models.Stock
+	nextCursor string // cursor after the last page whose rows are all in this or earlier batches
+}
+
+type writeResult struct {
+	index      int
+	nextCursor string
+	stored     repositories.StoreResult
+}
+
+// tracker owns the running totals, stages report through it so every event
+// carries consistent totals. onEvent is called once the lock is released, a
+// slow listener doesn't hold up the other stages.
+type tracker struct {
+	mu      sync.Mutex
+	stats   *SyncStats
+	source  string
+	onEvent func(SyncEvent)
+}
+
+func (t *tracker) update(apply func(stats *SyncStats), event *SyncEvent) {
+	t.mu.Lock()
+	apply(t.stats)
+	if event == nil || t.onEvent == nil {
+		t.mu.Unlock()
+		return
+	}
+	emitted := *event
+	emitted.Source = t.source
+	if emitted.Page == 0 {
+		emitted.Page = t.stats.Pages
+	}
+	emitted.Totals = *t.stats
+	emitted.Time = time.Now()
+	t.mu.Unlock()
+
+	t.onEvent(emitted)
+}
+
+// crawl runs one source through three stages connected by bounded channels:
+// a fetcher following the cursor, a converter building batches and a pool of
+// writers. The checkpoint only advances once every batch before it is stored,
+// writers may finish out of order.
+//
+// Only the fetcher stops with ctx or with its own failure. The later stages
+// drain what it already fetched, so those pages are stored and checkpointed
+// before crawl returns the fetch error, and their writes don't depend on ctx.
+// A failure of a later stage stops every stage.
+func crawl(ctx context.Context, repos *repositories.Repositories, source Source, opts SyncOptions, cfg config.PipelineConfig, policy repositories.ConflictPolicy, stats *SyncStats) error {
+	t := &tracker{stats: stats, source: source.Name(), onEvent: opts.OnEvent}
+
+	checkpoint, err := repos.Sync.GetCheckpoint(ctx, source.Name())
+	if err != nil {
+		return fmt.Errorf("Can't load sync checkpoint: Error %v", err)
+	}
+	cursor := ""
+	if opts.Restart || checkpoint.Completed {
+		checkpoint = models.SyncCheckpoint{Source: source.Name()}
+	} else if checkpoint.NextPage != "" {
+		cursor = checkpoint.NextPage
+		log.Printf("resuming sync of %s from page %s", source.Name(), cursor)
+	}
+
+	group, drainCtx := errgroup.WithContext(context.WithoutCancel(ctx))
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	defer cancelFetch()
+	stopFetch := context.AfterFunc(drainCtx, cancelFetch)
+	defer stopFetch()
+
+	pages := make(chan fetchedPage, cfg.BufferDepth)
+	batches := make(chan writeBatch, cfg.BufferDepth)
+	results := make(chan writeResult, cfg.BufferDepth)
+
+	fetchErr := make(chan error, 1)
+	go func() {
+		defer close(pages)
+		fetchErr <- fetchStage(fetchCtx, source, cursor, pages, t)
+	}()
+	group.Go(func() error {
+		defer close(batches)
+		return convertStage(drainCtx, repos.Quarantine, source.Name(), opts.JobID, cfg.BatchSize, pages, batches, t)
+	})
+
+	writer := repositories.NewBatchWriter(repos.Stocks, cfg.BatchSize, policy, opts.JobID)
+	var writers sync.WaitGroup
+	for i := 0; i < cfg.Writers; i++ {
+		writers.Add(1)
+		group.Go(func() error {
+			defer writers.Done()
+			return writeStage(drainCtx, writer, batches, results, t)
+		})
+	}
+	go func() {
+		writers.Wait()
+		close(results)
+	}()
+
+	group.Go(func() error {
+		return commitStage(drainCtx, repos.Sync, results, &checkpoint, t)
+	})
+
+	err = group.Wait()
+	cancelFetch()
+	if fetchErr := <-fetchErr; err == nil {
+		err = fetchErr
+	}
+	if err != nil {
+		return err
+	}
+
+	checkpoint.NextPage = ""
+	checkpoint.Completed = true
+	checkpoint.UpdatedAt = time.Now()
+	if err := repos.Sync.SaveCheckpoint(context.WithoutCancel(ctx), checkpoint); err != nil {
+		return fmt.Errorf("Can't save sync checkpoint: Error %v", err)
+	}
+	return nil
+}
+
+func fetchStage(ctx context.Context, source Source, cursor string, pages chan<- fetchedPage, t *tracker) error {
+	started := time.Now()
+	return Each(ctx, source, cursor, func(items []StockApi, cursor, nextCursor string) error {
+		fetched := time.Now()
+		t.update(func(stats *SyncStats) {
+			stats.Pages++
+			stats.Timings.FetchMs += fetched.Sub(started).Milliseconds()
+		}, &SyncEvent{Type: EventPageFetched, Cursor: nextCursor, Items: len(items)})
+
+		select {
+		case pages <- fetchedPage{items: items, cursor: cursor, nextCursor: nextCursor}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		started = time.Now()
+		t.update(func(stats *SyncStats) {
+			stats.Timings.StalledMs += started.Sub(fetched).Milliseconds()
+		}, nil)
+		return nil
+	})
+}
+
+func convertStage(ctx context.Context, quarantine *repositories.QuarantineRepository, source string, jobID uint, batchSize int, pages <-chan fetchedPage, batches chan<- writeBatch, t *tracker) error {
+	var pending []models.Stock
+	index := 0
+	received := "" // cursor after the last page received
+
+	send := func(stocks []models.Stock, nextCursor string) error {
+		select {
+		case batches <- writeBatch{index: index, stocks: stocks, nextCursor: nextCursor}:
+			index++
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for page := range pages {
+		started := time.Now()
+
+		var rejected []models.QuarantinedStock
+		for _, item := range page.items {
+			stock, err := ConvertStockApi(item)
+			if err != nil {
+				rejected = append(rejected, Quarantine(item, err, source, page.cursor, jobID))
+				continue
+			}
+			stock.Source = source
+			pending = append(pending, stock)
+		}
+		if len(rejected) > 0 {
+			if err := quarantine.StoreQuarantined(ctx, rejected); err != nil {
+				return fmt.Errorf("Can't quarantine rejected rows: Error %v", err)
+			}
+		}
+
+		elapsed := time.Since(started).Milliseconds()
+		var event *SyncEvent
+		if len(rejected) > 0 {
+			event = &SyncEvent{Type: EventRowsRejected, Rejected: len(rejected)}
+		}
+		t.update(func(stats *SyncStats) {
+			stats.Rejected += len(rejected)
+			stats.Timings.ConvertMs += elapsed
+		}, event)
+
+		// the last page flushes what's left
+		last := page.nextCursor == ""
+		for len(pending) >= batchSize || (last && len(pending) > 0) {
+			size := min(batchSize, len(pending))
+			// a batch that leaves rows of this page pending can only resume
+			// from this page, the one that empties it from the next
+			resume := page.cursor
+			if size == len(pending) {
+				resume = page.nextCursor
+			}
+			if err := send(pending[:size:size], resume); err != nil {
+				return err
+			}
+			pending = pending[size:]
+		}
+		if last {
+			return nil
+		}
+		received = page.nextCursor
+	}
+
+	// the fetcher stopped before the last page, the rows of the pages it
+	// fetched are still stored and the crawl resumes after them
+	if len(pending) > 0 {
+		return send(pending, received)
+	}
+	return nil
+}
+
+func writeStage(ctx context.Context, writer *repositories.BatchWriter, batches <-chan writeBatch, results chan<- writeResult, t *tracker) error {
+	for batch := range batches {
+		started := time.Now()
+		stored, err := writer.Write(ctx, batch.stocks)
+		elapsed := time.Since(started).Milliseconds()
+		t.update(func(stats *SyncStats) {
+			stats.Failed += stored.Failed
+			stats.Timings.WriteMs += elapsed
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("Can't store batch: Error %v", err)
+		}
+
+		select {
+		case results <- writeResult{index: batch.index, nextCursor: batch.nextCursor, stored: stored}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func commitStage(ctx context.Context, checkpoints *repositories.SyncRepository, results <-chan writeResult, checkpoint *models.SyncCheckpoint, t *tracker) error {
+	done := map[int]writeResult{}
+	next := 0
+
+	for {
+		var result writeResult
+		var ok bool
+		select {
+		case result, ok = <-results:
+			if !ok {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		t.update(func(stats *SyncStats) {
+			stats.Inserted += result.stored.Inserted
+			stats.Updated += result.stored.Updated
+			stats.Ignored += result.stored.Ignored
+		}, &SyncEvent{Type: EventBatchStored, Inserted: result.stored.Inserted, Updated: result.stored.Updated, Ignored: result.stored.Ignored})
+
+		done[result.index] = result
+		advanced := false
+		for {
+			stored, ok := done[next]
+			if !ok {
+				break
+			}
+			delete(done, next)
+			next++
+			advanced = true
+			checkpoint.NextPage = stored.nextCursor
+			checkpoint.Batches++
+		}
+		if !advanced {
+			continue
+		}
+
+		// everything up to this cursor is stored, so the crawl can resume from it
+		checkpoint.Completed = false
+		checkpoint.UpdatedAt = time.Now()
+		if err := checkpoints.SaveCheckpoint(ctx, *checkpoint); err != nil {
+			return fmt.Errorf("Can't save sync checkpoint: Error %v", err)
+		}
+	}
+}
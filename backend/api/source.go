@@ -0,0 +1,68 @@
+package api
+
+import (
+	"backend/config"
+	"context"
+	"fmt"
+)
+
+// Source is somewhere ratings are ingested from. Pages are addressed by an
+// opaque cursor: the empty cursor is the first page and FetchPage returns the
+// cursor of the following one, empty after the last page.
+type Source interface {
+	Name() string
+	FetchPage(ctx context.Context, cursor string) (items []StockApi, nextCursor string, err error)
+}
+
+// Each calls fn for every page of source starting at cursor, until the last
+// page or until fn returns an error.
+func Each(ctx context.Context, source Source, cursor string, fn func(items []StockApi, cursor, nextCursor string) error) error {
+	for {
+		items, nextCursor, err := source.FetchPage(ctx, cursor)
+		if err != nil {
+			return err
+		}
+		if err := fn(items, cursor, nextCursor); err != nil {
+			return err
+		}
+		if nextCursor == "" {
+			return nil
+		}
+		cursor = nextCursor
+	}
+}
+
+func NewSource(cfg config.SourceConfig) (Source, error) {
+	switch cfg.Kind {
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("source %s has no URL configured", cfg.Name)
+		}
+		return NewHTTPSource(cfg.Name, cfg.URL, cfg.Token), nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("source %s has no file path configured", cfg.Name)
+		}
+		return NewFileSource(cfg.Name, cfg.Path), nil
+	default:
+		return nil, fmt.Errorf("source %s has unknown kind %q", cfg.Name, cfg.Kind)
+	}
+}
+
+// LoadSources builds the sources selected by config.LoadSources.
+func LoadSources() ([]Source, error) {
+	configs, err := config.LoadSources()
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]Source, 0, len(configs))
+	for _, cfg := range configs {
+		source, err := NewSource(cfg)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
@@ -0,0 +1,63 @@
+// Command fakeupstream serves a fake ratings API for development:
+//
+//	fakeupstream -addr :8081 -token secret -items 5000 -error-rate 0.05
+//
+// then point the backend at it with API_URL=http://localhost:8081 and
+// API_TOKEN=secret.
+package main
+
+import (
+	"backend/fakeupstream"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "address to listen on")
+	token := flag.String("token", "", "required Authorization header, empty accepts any")
+	pageSize := flag.Int("page-size", 100, "items per page")
+	seed := flag.Uint64("seed", 1, "seed for generated data and fault rolls")
+	items := flag.Int("items", 1000, "ratings to generate when no fixture is given")
+	invalidRate := flag.Float64("invalid-rate", 0, "fraction of generated ratings with an unparsable target")
+	fixture := flag.String("fixture", "", ".json, .ndjson or .csv file to serve instead of generated data")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	errorRate := flag.Float64("error-rate", 0, "fraction of requests answered with 500")
+	rateLimitRate := flag.Float64("rate-limit-rate", 0, "fraction of requests answered with 429")
+	retryAfter := flag.Duration("retry-after", 0, "Retry-After sent with 429 responses")
+	malformedRate := flag.Float64("malformed-rate", 0, "fraction of requests answered with malformed JSON")
+	truncateRate := flag.Float64("truncate-rate", 0, "fraction of pages cut off halfway")
+	script := flag.String("script", "", "faults for the first requests in order, e.g. 500,429,ok,truncate")
+	flag.Parse()
+
+	faultScript, err := fakeupstream.ParseScript(*script)
+	if err != nil {
+		log.Fatalf("invalid -script: %v", err)
+	}
+
+	server, err := fakeupstream.New(fakeupstream.Config{
+		Token:       *token,
+		PageSize:    *pageSize,
+		Seed:        *seed,
+		Items:       *items,
+		InvalidRate: *invalidRate,
+		Fixture:     *fixture,
+		Faults: fakeupstream.Faults{
+			Latency:       *latency,
+			ErrorRate:     *errorRate,
+			RateLimitRate: *rateLimitRate,
+			RetryAfter:    *retryAfter,
+			MalformedRate: *malformedRate,
+			TruncateRate:  *truncateRate,
+			Script:        faultScript,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to start the fake upstream: %v", err)
+	}
+
+	log.Println("Fake upstream running in: http://localhost" + *addr)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("Failed to start the server: %v", err)
+	}
+}
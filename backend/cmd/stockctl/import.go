@@ -0,0 +1,53 @@
+package main
+
+import (
+	"backend/api"
+	"backend/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "csv or ndjson, guessed from the extension by default")
+	source := flags.String("source", "import", "source name recorded on the imported ratings")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("import takes exactly one file")
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = api.FormatFromPath(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	repos, err := openRepositories()
+	if err != nil {
+		return err
+	}
+
+	report, err := services.NewImportService(repos.Stocks).ImportRatings(context.Background(), file, *format, *source)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", report.Failed, report.Rows)
+	}
+	return nil
+}
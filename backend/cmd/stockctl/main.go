@@ -0,0 +1,63 @@
+// Command stockctl runs maintenance tasks against the stock database.
+//
+//	stockctl import [-format csv|ndjson] [-source name] <file>
+//	stockctl sync [-restart] [-dry-run]
+//	stockctl migrate up [-to version] | down [-steps n] | status
+//	stockctl openapi [check]
+package main
+
+import (
+	"backend/config"
+	"backend/db"
+	"backend/repositories"
+	"fmt"
+	"os"
+)
+
+func main() {
+	config.LoadEnv()
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "sync":
+		err = runSync(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "openapi":
+		err = runOpenAPI(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "stockctl:", err)
+		os.Exit(1)
+	}
+}
+
+// openRepositories opens the shared pool, main closes it on the way out.
+func openRepositories() (*repositories.Repositories, error) {
+	conn, err := db.Init(config.LoadDB())
+	if err != nil {
+		return nil, err
+	}
+	return repositories.NewRepositories(conn), nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	fmt.Fprintln(os.Stderr, "  stockctl import [-format csv|ndjson] [-source name] <file>")
+	fmt.Fprintln(os.Stderr, "  stockctl sync [-restart] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "  stockctl migrate up [-to version] | down [-steps n] | status")
+	fmt.Fprintln(os.Stderr, "  stockctl openapi [check]")
+	os.Exit(2)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"backend/config"
+	"backend/db"
+	"backend/migrations"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		usage()
+	}
+
+	cfg := config.LoadDB()
+	conn, err := db.Open(cfg.Driver, cfg.URL)
+	if err != nil {
+		return fmt.Errorf("can't open the database: %v", err)
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch args[0] {
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ExitOnError)
+		to := flags.Int("to", 0, "version to migrate up to, the latest by default")
+		flags.Parse(args[1:])
+
+		done, err := migrations.Up(conn, *to)
+		printMigrations("applied", done, err)
+		return err
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		flags.Parse(args[1:])
+
+		done, err := migrations.Down(conn, *steps)
+		printMigrations("rolled back", done, err)
+		return err
+	case "status":
+		statuses, err := migrations.GetStatus(conn)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		usage()
+	}
+	return nil
+}
+
+// printMigrations lists done, the error of a run that did nothing speaks for
+// itself.
+func printMigrations(verb string, done []migrations.Migration, err error) {
+	if len(done) == 0 && err == nil {
+		fmt.Println("nothing to do")
+	}
+	for _, m := range done {
+		fmt.Println(verb, m.Version, m.Name)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"backend/handlers"
+	"backend/openapi"
+	"backend/repositories"
+	"backend/routes"
+	"fmt"
+	"os"
+)
+
+// runOpenAPI prints the OpenAPI document, or with "check" fails when a
+// route of the server isn't described or a described one isn't served.
+func runOpenAPI(args []string) error {
+	if len(args) == 0 {
+		_, err := os.Stdout.Write(append(openapi.JSON(), '\n'))
+		return err
+	}
+	if args[0] != "check" {
+		usage()
+	}
+
+	// the routes don't touch the repository until they serve a request
+	router := routes.StockRoutes(routes.Handlers{
+		Stocks: handlers.NewStockHandler(repositories.NewMemoryStockRepository()),
+	})
+	undescribed, unserved, err := openapi.Check(router)
+	if err != nil {
+		return err
+	}
+	for _, route := range undescribed {
+		fmt.Println("not described:", route)
+	}
+	for _, route := range unserved {
+		fmt.Println("not served:   ", route)
+	}
+	if len(undescribed) > 0 || len(unserved) > 0 {
+		return fmt.Errorf("the OpenAPI document is out of date with the routes")
+	}
+	fmt.Println("every route is described")
+	return nil
+}
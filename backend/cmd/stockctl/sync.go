@@ -0,0 +1,56 @@
+package main
+
+import (
+	"backend/models"
+	"backend/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func runSync(args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	restart := flags.Bool("restart", false, "ignore the checkpoints and crawl from the first page")
+	dryRun := flags.Bool("dry-run", false, "report what the sync would change without writing anything")
+	flags.Parse(args)
+
+	repos, err := openRepositories()
+	if err != nil {
+		return err
+	}
+	// an interrupted sync still records its job as failed before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	syncs := services.NewSyncService(ctx, repos)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if *dryRun {
+		report, err := syncs.DryRunSync(ctx)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(report)
+	}
+
+	job, _, err := syncs.StartSync(*restart)
+	if err != nil {
+		return err
+	}
+	job, err = syncs.WaitSync(context.Background(), job.ID)
+	if err != nil {
+		return err
+	}
+	if err := encoder.Encode(job); err != nil {
+		return err
+	}
+	if job.State == models.SyncJobFailed {
+		return fmt.Errorf("sync job %d failed: %s", job.ID, job.Error)
+	}
+	return nil
+}
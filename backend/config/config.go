@@ -1,21 +1,36 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type DBConfig struct {
-	URL       string
-	User      string
-	Pass      string
-	Host      string
-	Port      string
-	Name      string
-	Mode      string
-	TableName string
+	Driver string
+	URL    string
+	User   string
+	Pass   string
+	Host   string
+	Port   string
+	Name   string
+	Mode   string
+	Pool   PoolConfig
+	// AutoMigrate applies the pending migrations when the pool is opened,
+	// turn it off to run them with stockctl migrate instead.
+	AutoMigrate bool
+}
+
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 type ExternalApi struct {
@@ -23,6 +38,45 @@ type ExternalApi struct {
 	Token string
 }
 
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Timeout     time.Duration
+}
+
+type ScheduleConfig struct {
+	Interval   time.Duration
+	Cron       string
+	Jitter     time.Duration
+	QuietHours string
+}
+
+type PipelineConfig struct {
+	BatchSize      int
+	BufferDepth    int
+	Writers        int
+	ConflictPolicy string
+}
+
+type ArchiveConfig struct {
+	RetentionMaxAge   time.Duration // stocks rated longer ago are archived, 0 disables retention
+	RetentionInterval time.Duration
+	SnapshotDir       string
+}
+
+type AdminConfig struct {
+	Token string
+}
+
+type SourceConfig struct {
+	Name  string
+	Kind  string
+	URL   string
+	Token string
+	Path  string
+}
+
 func LoadEnv() {
 	err := godotenv.Load()
 	if err != nil {
@@ -32,14 +86,25 @@ func LoadEnv() {
 
 func LoadDB() DBConfig {
 	return DBConfig{
-		URL:       os.Getenv("DATABASE_URL"),
-		User:      os.Getenv("SQL_USER"),
-		Pass:      os.Getenv("GENERATED_PASSWORD"),
-		Host:      os.Getenv("CLUSTER_HOST"),
-		Port:      os.Getenv("CLUSTER_PORT"),
-		Name:      os.Getenv("CLUSTER_NAME"),
-		Mode:      os.Getenv("DB_SSL_MODE"),
-		TableName: os.Getenv("TABLE_NAME"),
+		Driver:      os.Getenv("DB_DRIVER"),
+		URL:         os.Getenv("DATABASE_URL"),
+		User:        os.Getenv("SQL_USER"),
+		Pass:        os.Getenv("GENERATED_PASSWORD"),
+		Host:        os.Getenv("CLUSTER_HOST"),
+		Port:        os.Getenv("CLUSTER_PORT"),
+		Name:        os.Getenv("CLUSTER_NAME"),
+		Mode:        os.Getenv("DB_SSL_MODE"),
+		Pool:        LoadPool(),
+		AutoMigrate: getEnvBool("DB_AUTO_MIGRATE", true),
+	}
+}
+
+func LoadPool() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 20),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 10),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
 	}
 }
 
@@ -50,6 +115,121 @@ func LoadApi() ExternalApi {
 	}
 }
 
+// LoadSources reads SYNC_SOURCES, a comma separated list of name:kind[:path]
+// entries, e.g. "upstream:http,history:file:/data/ratings.csv". HTTP sources
+// read their URL and token from <NAME>_API_URL and <NAME>_API_TOKEN, except
+// the default "upstream" source which keeps using API_URL and API_TOKEN.
+func LoadSources() ([]SourceConfig, error) {
+	value := os.Getenv("SYNC_SOURCES")
+	if value == "" {
+		value = "upstream:http"
+	}
+
+	var sources []SourceConfig
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid SYNC_SOURCES entry %q, expected name:kind[:path]", entry)
+		}
+
+		source := SourceConfig{Name: parts[0], Kind: parts[1]}
+		if len(parts) == 3 {
+			source.Path = parts[2]
+		}
+		if source.Kind == "http" {
+			if source.Name == "upstream" {
+				source.URL = LoadApi().URL
+				source.Token = LoadApi().Token
+			} else {
+				prefix := strings.ToUpper(source.Name)
+				source.URL = os.Getenv(prefix + "_API_URL")
+				source.Token = os.Getenv(prefix + "_API_TOKEN")
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func LoadRetry() RetryConfig {
+	return RetryConfig{
+		MaxAttempts: getEnvInt("API_MAX_ATTEMPTS", 5),
+		BaseDelay:   getEnvDuration("API_RETRY_BASE_DELAY", 500*time.Millisecond),
+		MaxDelay:    getEnvDuration("API_RETRY_MAX_DELAY", 30*time.Second),
+		Timeout:     getEnvDuration("API_TIMEOUT", 30*time.Second),
+	}
+}
+
+func LoadPipeline() PipelineConfig {
+	cfg := PipelineConfig{
+		BatchSize:      getEnvInt("SYNC_BATCH_SIZE", 100),
+		BufferDepth:    getEnvInt("SYNC_BUFFER_DEPTH", 4),
+		Writers:        getEnvInt("SYNC_WRITERS", 2),
+		ConflictPolicy: os.Getenv("SYNC_CONFLICT_POLICY"),
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BufferDepth < 0 {
+		cfg.BufferDepth = 0
+	}
+	if cfg.Writers < 1 {
+		cfg.Writers = 1
+	}
+	return cfg
+}
+
+func LoadSchedule() ScheduleConfig {
+	return ScheduleConfig{
+		Interval:   getEnvDuration("SYNC_INTERVAL", 0),
+		Cron:       os.Getenv("SYNC_CRON"),
+		Jitter:     getEnvDuration("SYNC_JITTER", 0),
+		QuietHours: os.Getenv("SYNC_QUIET_HOURS"),
+	}
+}
+
+func LoadArchive() ArchiveConfig {
+	snapshotDir := os.Getenv("SNAPSHOT_DIR")
+	if snapshotDir == "" {
+		snapshotDir = "snapshots"
+	}
+	return ArchiveConfig{
+		RetentionMaxAge:   getEnvDuration("RETENTION_MAX_AGE", 0),
+		RetentionInterval: getEnvDuration("RETENTION_INTERVAL", 24*time.Hour),
+		SnapshotDir:       snapshotDir,
+	}
+}
+
+// LoadAdmin reads the token of the admin endpoints, they are disabled while
+// ADMIN_TOKEN is empty.
+func LoadAdmin() AdminConfig {
+	return AdminConfig{Token: os.Getenv("ADMIN_TOKEN")}
+}
+
 func LoadPort() string {
 	return os.Getenv("PORT")
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -2,49 +2,124 @@ package db
 
 import (
 	"backend/config"
+	"errors"
 	"fmt"
 	"log"
-	"backend/models"
+	"backend/migrations"
+	"strings"
+	"sync"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+// ErrClosed is returned once Close ran, the pool is never opened again.
+var ErrClosed = errors.New("the database is closed")
 
-func Conect() (*gorm.DB, error) {
-	var err error
-	dsn := config.LoadDB().URL
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("Can't get the conection with the database %v", err)
+var (
+	mu       sync.Mutex
+	pool     *gorm.DB // the shared handle, opened by Init and closed by Close
+	migrated bool
+	closed   bool
+)
+
+// migrate applies the pending migrations, unless cfg turns it off. Callers
+// hold mu.
+func migrate(conn *gorm.DB, cfg config.DBConfig) error {
+	if migrated || !cfg.AutoMigrate {
+		return nil
 	}
 
-	log.Println("Conection with :", DB.Statement.Table, " Established")
+	done, err := migrations.Up(conn, 0)
+	for _, m := range done {
+		log.Println("Applied migration", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	migrated = true
+	return nil
+}
 
-	if DB.Migrator().HasTable(config.LoadDB().TableName) {
-		log.Println("The table: ", config.LoadDB().TableName, " exist")
-	} else {
-		log.Println("The table: ", config.LoadDB().TableName, " doesn't exist")
-		if err := DB.AutoMigrate(&models.Stock{}); err != nil {
-			return nil, fmt.Errorf("Failed to migrate: %v", err)
+// Open opens a handle for driver: "postgres" (the default, CockroachDB speaks
+// its protocol) or "sqlite", in which case dsn is the database file.
+func Open(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "", "postgres":
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case "sqlite":
+		// concurrent writers wait for the lock instead of failing right away
+		if !strings.Contains(dsn, "busy_timeout") {
+			separator := "?"
+			if strings.Contains(dsn, "?") {
+				separator = "&"
+			}
+			dsn += separator + "_pragma=busy_timeout(5000)"
 		}
+		return gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
 	}
-	return DB, nil
 }
 
-func Drop() error {
-	var err error
-	dsn := config.LoadDB().URL
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return fmt.Errorf("can't get the conection with the database %v", err)
+// Init opens the shared pool and migrates the tables. It is meant to run once
+// at startup, calling it again returns the handle already open.
+func Init(cfg config.DBConfig) (*gorm.DB, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+	if pool == nil {
+		conn, err := Open(cfg.Driver, cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("Can't get the conection with the database %v", err)
+		}
+		if err := configurePool(conn, cfg); err != nil {
+			return nil, err
+		}
+		pool = conn
+		log.Println("Conection with the database established")
 	}
 
-	if err := DB.Migrator().DropTable(config.LoadDB().TableName); err != nil {
-		return fmt.Errorf("failed to drop table: %v", err)
+	if err := migrate(pool, cfg); err != nil {
+		return nil, fmt.Errorf("Failed to migrate: %v", err)
 	}
-	
-	fmt.Println("the database has been deleted")
+	return pool, nil
+}
 
+func configurePool(conn *gorm.DB, cfg config.DBConfig) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return fmt.Errorf("can't get the connection pool: %v", err)
+	}
+	limits := cfg.Pool
+	// every connection to ":memory:" would see its own empty database
+	if cfg.Driver == "sqlite" && strings.Contains(cfg.URL, ":memory:") {
+		limits.MaxOpenConns = 1
+	}
+	sqlDB.SetMaxOpenConns(limits.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(limits.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(limits.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(limits.ConnMaxIdleTime)
 	return nil
-}
\ No newline at end of file
+}
+
+// Close closes the shared pool, waiting for the queries in flight.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	if pool == nil {
+		return nil
+	}
+	sqlDB, err := pool.DB()
+	if err != nil {
+		return fmt.Errorf("can't get the connection pool: %v", err)
+	}
+	pool = nil
+	migrated = false
+	return sqlDB.Close()
+}
@@ -0,0 +1,13 @@
+package dto
+
+// ErrorResponse is the body of every error, on /api and /api/v2 alike.
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
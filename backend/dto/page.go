@@ -0,0 +1,51 @@
+package dto
+
+// Pagination describes a page of a list. Offset mode fills page,
+// total_items and total_pages. Cursor mode fills next_cursor and
+// prev_cursor, absent at either end, and total_items and approximate when
+// the total was asked for.
+type Pagination struct {
+	Mode        string `json:"mode"` // "offset" or "cursor"
+	PageSize    int    `json:"page_size"`
+	Page        int    `json:"page,omitempty"`
+	TotalItems  *int   `json:"total_items,omitempty"`
+	TotalPages  *int   `json:"total_pages,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	PrevCursor  string `json:"prev_cursor,omitempty"`
+	Approximate *bool  `json:"approximate,omitempty"`
+}
+
+func OffsetPagination(page, pageSize, totalItems int) Pagination {
+	totalPages := totalItems / pageSize
+	if totalItems%pageSize != 0 {
+		totalPages += 1
+	}
+	return Pagination{
+		Mode:       "offset",
+		PageSize:   pageSize,
+		Page:       page,
+		TotalItems: &totalItems,
+		TotalPages: &totalPages,
+	}
+}
+
+func CursorPagination(pageSize int, next, prev string) Pagination {
+	return Pagination{
+		Mode:       "cursor",
+		PageSize:   pageSize,
+		NextCursor: next,
+		PrevCursor: prev,
+	}
+}
+
+// WithTotal adds the total of a cursor page.
+func (p Pagination) WithTotal(totalItems int, approximate bool) Pagination {
+	p.TotalItems = &totalItems
+	p.Approximate = &approximate
+	return p
+}
+
+type StockPage struct {
+	Items      []Stock    `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"backend/models"
+	"time"
+)
+
+type Recommendation struct {
+	Ticker     string    `json:"ticker"`
+	Company    string    `json:"company"`
+	Score      float64   `json:"score"`
+	Reason     string    `json:"reason"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// RecommendationList is the body of /api/v2/recommendations.
+type RecommendationList struct {
+	Items []Recommendation `json:"items"`
+}
+
+func NewRecommendationList(recommendations []models.Recommendation) RecommendationList {
+	list := RecommendationList{Items: make([]Recommendation, 0, len(recommendations))}
+	for _, r := range recommendations {
+		list.Items = append(list.Items, Recommendation{
+			Ticker:     r.Ticker,
+			Company:    r.Company,
+			Score:      r.Score,
+			Reason:     r.Reason,
+			LastUpdate: r.LastUpdate,
+		})
+	}
+	return list
+}
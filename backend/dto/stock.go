@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"backend/models"
+	"math"
+	"time"
+)
+
+// Stock is a rating as /api/v2 writes it.
+type Stock struct {
+	Ticker     string    `json:"ticker"`
+	Company    string    `json:"company"`
+	Brokerage  string    `json:"brokerage"`
+	Action     string    `json:"action"`
+	RatingFrom string    `json:"rating_from"`
+	RatingTo   string    `json:"rating_to"`
+	TargetFrom float64   `json:"target_from"`
+	TargetTo   float64   `json:"target_to"`
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+
+	TargetChange    float64  `json:"target_change"`     // target_to - target_from
+	TargetChangePct *float64 `json:"target_change_pct"` // in percent of target_from, null when target_from is 0
+}
+
+func NewStock(stock models.Stock) Stock {
+	s := Stock{
+		Ticker:       stock.Ticker,
+		Company:      stock.Company,
+		Brokerage:    stock.Brokerage,
+		Action:       stock.Action,
+		RatingFrom:   stock.RatingFrom,
+		RatingTo:     stock.RatingTo,
+		TargetFrom:   stock.TargetFrom,
+		TargetTo:     stock.TargetTo,
+		Time:         stock.Time,
+		Source:       stock.Source,
+		TargetChange: round(stock.TargetTo - stock.TargetFrom),
+	}
+	if stock.TargetFrom != 0 {
+		pct := round((stock.TargetTo - stock.TargetFrom) * 100 / stock.TargetFrom)
+		s.TargetChangePct = &pct
+	}
+	return s
+}
+
+func NewStocks(stocks []models.Stock) []Stock {
+	items := make([]Stock, 0, len(stocks))
+	for _, stock := range stocks {
+		items = append(items, NewStock(stock))
+	}
+	return items
+}
+
+// round keeps the cents, the targets are prices.
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
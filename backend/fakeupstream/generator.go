@@ -0,0 +1,53 @@
+package fakeupstream
+
+import (
+	"backend/api"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	companies = []struct{ ticker, name string }{
+		{"AAPL", "Apple Inc."}, {"MSFT", "Microsoft Corporation"}, {"GOOGL", "Alphabet Inc."},
+		{"AMZN", "Amazon.com, Inc."}, {"NVDA", "NVIDIA Corporation"}, {"META", "Meta Platforms, Inc."},
+		{"TSLA", "Tesla, Inc."}, {"JPM", "JPMorgan Chase & Co."}, {"V", "Visa Inc."},
+		{"KO", "The Coca-Cola Company"}, {"PFE", "Pfizer Inc."}, {"NFLX", "Netflix, Inc."},
+	}
+	brokerages = []string{"Goldman Sachs", "Morgan Stanley", "JPMorgan", "Barclays", "UBS Group", "Wells Fargo", "Citigroup"}
+	actions    = []string{"upgraded by", "downgraded by", "target raised by", "target lowered by", "reiterated by", "initiated by"}
+	ratings    = []string{"Buy", "Strong-Buy", "Outperform", "Overweight", "Neutral", "Hold", "Equal Weight", "Underperform", "Sell"}
+)
+
+// Generate returns count ratings derived only from seed, so the same seed
+// always serves the same data. A fraction invalidRate of them carries a
+// target price ConvertStockApi can't parse.
+func Generate(seed uint64, count int, invalidRate float64) []api.StockApi {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	items := make([]api.StockApi, 0, count)
+	for i := 0; i < count; i++ {
+		company := companies[rng.IntN(len(companies))]
+		from := 20 + rng.Float64()*480
+		to := from * (0.7 + rng.Float64()*0.6)
+
+		item := api.StockApi{
+			Ticker:     company.ticker,
+			TargetFrom: fmt.Sprintf("$%.2f", from),
+			TargetTo:   fmt.Sprintf("$%.2f", to),
+			Company:    company.name,
+			Action:     actions[rng.IntN(len(actions))],
+			Brokerage:  brokerages[rng.IntN(len(brokerages))],
+			RatingFrom: ratings[rng.IntN(len(ratings))],
+			RatingTo:   ratings[rng.IntN(len(ratings))],
+			// one event per minute keeps every (ticker, time) unique
+			Time: start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339Nano),
+		}
+		if rng.Float64() < invalidRate {
+			item.TargetTo = "N/A"
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,219 @@
+// Package fakeupstream is a stand-in for the ratings API: it serves the same
+// {items, next_page} pages from a fixture file or a seeded generator and can
+// inject faults, so the ingestion pipeline can be exercised offline.
+package fakeupstream
+
+import (
+	"backend/api"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FaultNone      = "ok"
+	FaultError     = "500"
+	FaultRateLimit = "429"
+	FaultMalformed = "malformed"
+	FaultTruncate  = "truncate"
+)
+
+// Faults are injected per request. Script is applied first, one entry per
+// request in order (e.g. "500", "429", "ok"), then every request rolls the
+// rates with the server's seeded generator.
+type Faults struct {
+	Latency       time.Duration
+	ErrorRate     float64
+	RateLimitRate float64
+	RetryAfter    time.Duration
+	MalformedRate float64
+	TruncateRate  float64
+	Script        []string
+}
+
+type Config struct {
+	Token       string // expected Authorization header, empty accepts anything
+	PageSize    int
+	Seed        uint64
+	Items       int     // how many ratings to generate when there's no fixture
+	InvalidRate float64 // fraction of generated ratings ConvertStockApi rejects
+	Fixture     string  // .json, .ndjson or .csv file to serve instead
+	Faults      Faults
+}
+
+type Server struct {
+	cfg   Config
+	items []api.StockApi
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	requests int
+}
+
+func New(cfg Config) (*Server, error) {
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = 100
+	}
+
+	var items []api.StockApi
+	if cfg.Fixture != "" {
+		var err error
+		items, err = readFixture(cfg.Fixture)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		items = Generate(cfg.Seed, cfg.Items, cfg.InvalidRate)
+	}
+
+	return &Server{
+		cfg:   cfg,
+		items: items,
+		rng:   rand.New(rand.NewPCG(cfg.Seed, cfg.Seed+1)),
+	}, nil
+}
+
+// Requests returns how many requests the server has answered.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fault := s.nextFault()
+
+	if s.cfg.Faults.Latency > 0 {
+		select {
+		case <-time.After(s.cfg.Faults.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if s.cfg.Token != "" && r.Header.Get("Authorization") != s.cfg.Token {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	switch fault {
+	case FaultError:
+		http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
+		return
+	case FaultRateLimit:
+		if s.cfg.Faults.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.cfg.Faults.RetryAfter.Seconds())))
+		}
+		http.Error(w, `{"error":"too many requests"}`, http.StatusTooManyRequests)
+		return
+	case FaultMalformed:
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"items": [{"ticker": "AAPL", "target_from": }`)
+		return
+	}
+
+	offset := 0
+	if cursor := r.URL.Query().Get("next_page"); cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 || offset > len(s.items) {
+			http.Error(w, `{"error":"invalid next_page"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	end := min(offset+s.cfg.PageSize, len(s.items))
+	page := api.ResponeStruct{Items: s.items[offset:end]}
+	if end < len(s.items) {
+		page.NextPage = strconv.Itoa(end)
+	}
+	if page.Items == nil {
+		page.Items = []api.StockApi{}
+	}
+
+	body, err := json.Marshal(page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fault == FaultTruncate {
+		// claim the full length and hang up halfway, like a dropped connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		body = body[:len(body)/2]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func (s *Server) nextFault() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request := s.requests
+	s.requests++
+
+	faults := s.cfg.Faults
+	if request < len(faults.Script) {
+		return faults.Script[request]
+	}
+
+	roll := s.rng.Float64()
+	for _, candidate := range []struct {
+		fault string
+		rate  float64
+	}{
+		{FaultError, faults.ErrorRate},
+		{FaultRateLimit, faults.RateLimitRate},
+		{FaultMalformed, faults.MalformedRate},
+		{FaultTruncate, faults.TruncateRate},
+	} {
+		if roll < candidate.rate {
+			return candidate.fault
+		}
+		roll -= candidate.rate
+	}
+	return FaultNone
+}
+
+func readFixture(path string) ([]api.StockApi, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open fixture: %v", err)
+	}
+	defer file.Close()
+
+	format := api.FormatFromPath(path)
+	if format == api.FormatJSON {
+		var page api.ResponeStruct
+		if err := json.NewDecoder(file).Decode(&page); err != nil {
+			return nil, fmt.Errorf("can't decode fixture %s: %v", path, err)
+		}
+		return page.Items, nil
+	}
+	return api.Decode(file, format)
+}
+
+// ParseScript splits a comma separated fault script, e.g. "500,429,ok".
+func ParseScript(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var script []string
+	for _, fault := range strings.Split(value, ",") {
+		fault = strings.TrimSpace(fault)
+		switch fault {
+		case FaultNone, FaultError, FaultRateLimit, FaultMalformed, FaultTruncate:
+			script = append(script, fault)
+		default:
+			return nil, fmt.Errorf("unknown fault %q", fault)
+		}
+	}
+	return script, nil
+}
@@ -0,0 +1,177 @@
+package fakeupstream
+
+import (
+	"backend/api"
+	"backend/repositories"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testToken = "secret"
+
+// runSync runs api.FetchData against server with the ratings stored in repos.
+func runSync(t *testing.T, server *Server, repos *repositories.Repositories) (api.SyncStats, error) {
+	t.Helper()
+	upstream := httptest.NewServer(server)
+	t.Cleanup(upstream.Close)
+
+	source := api.NewHTTPSource("fake", upstream.URL, testToken)
+	return api.FetchData(context.Background(), repos, api.SyncOptions{Sources: []api.Source{source}})
+}
+
+func newRepositories(t *testing.T) *repositories.Repositories {
+	t.Helper()
+	t.Setenv("API_MAX_ATTEMPTS", "3")
+	t.Setenv("API_RETRY_BASE_DELAY", "1ms")
+	t.Setenv("API_RETRY_MAX_DELAY", "5ms")
+
+	repos, err := repositories.NewSQLiteRepositories(":memory:")
+	if err != nil {
+		t.Fatalf("can't open the repositories: %v", err)
+	}
+	return repos
+}
+
+func newServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	cfg.Token = testToken
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("can't start the fake upstream: %v", err)
+	}
+	return server
+}
+
+func countStocks(t *testing.T, repos *repositories.Repositories) int {
+	t.Helper()
+	count, err := repos.Archive.CountStocks(context.Background())
+	if err != nil {
+		t.Fatalf("can't count the stocks: %v", err)
+	}
+	return count
+}
+
+func TestFetchDataStoresEveryPage(t *testing.T) {
+	repos := newRepositories(t)
+	server := newServer(t, Config{Seed: 1, Items: 250})
+
+	stats, err := runSync(t, server, repos)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Pages != 3 || stats.Inserted != 250 {
+		t.Errorf("got %d pages and %d inserted, want 3 and 250", stats.Pages, stats.Inserted)
+	}
+	if got := countStocks(t, repos); got != 250 {
+		t.Errorf("got %d stocks stored, want 250", got)
+	}
+
+	checkpoint, err := repos.Sync.GetCheckpoint(context.Background(), "fake")
+	if err != nil {
+		t.Fatalf("can't get the checkpoint: %v", err)
+	}
+	if !checkpoint.Completed {
+		t.Errorf("got checkpoint %+v, want it completed", checkpoint)
+	}
+}
+
+func TestFetchDataRetriesTransientFaults(t *testing.T) {
+	repos := newRepositories(t)
+	server := newServer(t, Config{Seed: 1, Items: 250, Faults: Faults{
+		Script: []string{FaultError, FaultRateLimit, FaultNone, FaultTruncate, FaultNone},
+	}})
+
+	stats, err := runSync(t, server, repos)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Inserted != 250 {
+		t.Errorf("got %d inserted, want 250", stats.Inserted)
+	}
+	// 3 pages plus the 3 faults retried
+	if got := server.Requests(); got != 6 {
+		t.Errorf("got %d requests, want 6", got)
+	}
+}
+
+func TestFetchDataQuarantinesInvalidRows(t *testing.T) {
+	repos := newRepositories(t)
+	server := newServer(t, Config{Seed: 2, Items: 300, InvalidRate: 0.2})
+
+	stats, err := runSync(t, server, repos)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Rejected == 0 {
+		t.Fatalf("got no rejected rows, want about a fifth of 300")
+	}
+	if stats.Inserted+stats.Rejected != 300 {
+		t.Errorf("got %d inserted and %d rejected, want 300 in total", stats.Inserted, stats.Rejected)
+	}
+
+	_, quarantined, err := repos.Quarantine.GetQuarantined(context.Background(), repositories.QuarantineFilter{}, 1, 1)
+	if err != nil {
+		t.Fatalf("can't get the quarantine: %v", err)
+	}
+	if quarantined != stats.Rejected {
+		t.Errorf("got %d quarantined rows, want %d", quarantined, stats.Rejected)
+	}
+}
+
+// TestFetchDataResumes checks the pages fetched before a failure are stored
+// and checkpointed, and that the next sync picks up from there.
+func TestFetchDataResumes(t *testing.T) {
+	repos := newRepositories(t)
+	failing := newServer(t, Config{Seed: 3, Items: 500, Faults: Faults{
+		Script: []string{FaultNone, FaultNone, FaultMalformed},
+	}})
+
+	_, err := runSync(t, failing, repos)
+	var decodeErr *api.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("got %v, want a DecodeError", err)
+	}
+	if got := countStocks(t, repos); got != 200 {
+		t.Errorf("got %d stocks stored before the failure, want 200", got)
+	}
+
+	checkpoint, err := repos.Sync.GetCheckpoint(context.Background(), "fake")
+	if err != nil {
+		t.Fatalf("can't get the checkpoint: %v", err)
+	}
+	if checkpoint.NextPage != "200" || checkpoint.Completed {
+		t.Errorf("got checkpoint %+v, want the next page at 200", checkpoint)
+	}
+
+	resumed := newServer(t, Config{Seed: 3, Items: 500})
+	stats, err := runSync(t, resumed, repos)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Pages != 3 || stats.Inserted != 300 {
+		t.Errorf("got %d pages and %d inserted, want the 3 pages left and 300", stats.Pages, stats.Inserted)
+	}
+	if got := countStocks(t, repos); got != 500 {
+		t.Errorf("got %d stocks stored, want 500", got)
+	}
+}
+
+func TestFetchDataRejectsWrongToken(t *testing.T) {
+	repos := newRepositories(t)
+	server, err := New(Config{Token: "other", Seed: 1, Items: 100})
+	if err != nil {
+		t.Fatalf("can't start the fake upstream: %v", err)
+	}
+
+	_, err = runSync(t, server, repos)
+	var statusErr *api.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("got %v, want an HTTPStatusError with status 401", err)
+	}
+	if got := server.Requests(); got != 1 {
+		t.Errorf("got %d requests, want 1, a 401 isn't retried", got)
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/services"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RequireAdmin lets through the requests carrying "Authorization: Bearer
+// <ADMIN_TOKEN>". Every request is refused while ADMIN_TOKEN is unset.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := config.LoadAdmin().Token
+		if token == "" {
+			writeError(w, r, newAPIError(http.StatusForbidden, "admin endpoints are disabled, set ADMIN_TOKEN to enable them", nil))
+			return
+		}
+
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, r, newAPIError(http.StatusUnauthorized, "admin token required", nil))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminHandler serves the archive, snapshot and purge endpoints.
+type AdminHandler struct {
+	archive *services.ArchiveService
+}
+
+func NewAdminHandler(archive *services.ArchiveService) *AdminHandler {
+	return &AdminHandler{archive: archive}
+}
+
+// ArchiveStocks moves the stocks rated before ?before= (RFC3339) to the
+// archive table.
+func (h *AdminHandler) ArchiveStocks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/admin/archive")
+
+	value := r.URL.Query().Get("before")
+	if !requiredParam(w, r, "before", value) {
+		return
+	}
+	before, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		writeError(w, r, invalidParam("before", value, "invalid before, expected RFC3339"))
+		return
+	}
+
+	archived, err := h.archive.Archive(r.Context(), before)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to archive: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message":  "stocks archived",
+		"archived": archived,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RestoreArchive moves the archived stocks rated at or after ?since=
+// (RFC3339, every archived stock by default) back to the stocks table.
+func (h *AdminHandler) RestoreArchive(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/admin/archive/restore")
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, r, invalidParam("since", value, "invalid since, expected RFC3339"))
+			return
+		}
+	}
+
+	restored, err := h.archive.Restore(r.Context(), since)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to restore: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message":  "archived stocks restored",
+		"restored": restored,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *AdminHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/admin/snapshots")
+
+	snapshots, err := h.archive.ListSnapshots()
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to list snapshots: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+func (h *AdminHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for POST /api/admin/snapshots")
+
+	snapshot, err := h.archive.TakeSnapshot(r.Context(), "manual")
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to take snapshot: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+func (h *AdminHandler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/admin/snapshots/{name}/restore")
+
+	result, err := h.archive.RestoreSnapshot(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to restore: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message": "snapshot restored",
+		"result":  result,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// PurgeStocks deletes every stored stock. Without ?confirm= it only answers
+// 428 with the token to repeat the request with, the deletion itself is
+// preceded by a snapshot.
+func (h *AdminHandler) PurgeStocks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for DELETE /api/admin/stocks")
+
+	token := r.URL.Query().Get("confirm")
+	if token == "" {
+		confirmation, err := h.archive.RequestPurge(r.Context())
+		if err != nil {
+			writeError(w, r, fmt.Errorf("failed to prepare the purge: %w", err))
+			return
+		}
+
+		resp := map[string]interface{}{
+			"message":      "repeat the request with ?confirm=<confirm_token> to delete every stock",
+			"confirmation": confirmation,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionRequired)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	result, err := h.archive.PurgeStocks(r.Context(), token)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to purge: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message": "stocks deleted",
+		"result":  result,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
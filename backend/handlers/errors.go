@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"backend/dto"
+	"backend/repositories"
+	"backend/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// APIError is what a handler answers when a request fails, written by
+// writeError as a dto.ErrorResponse.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// errorCodes are the codes of the statuses the handlers answer with.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:           "bad_request",
+	http.StatusUnauthorized:         "unauthorized",
+	http.StatusForbidden:            "forbidden",
+	http.StatusNotFound:             "not_found",
+	http.StatusMethodNotAllowed:     "method_not_allowed",
+	http.StatusNotAcceptable:        "not_acceptable",
+	http.StatusConflict:             "conflict",
+	http.StatusUnprocessableEntity:  "unprocessable_entity",
+	http.StatusPreconditionRequired: "confirmation_required",
+	http.StatusInternalServerError:  "internal_error",
+	http.StatusServiceUnavailable:   "unavailable",
+}
+
+// internalErrorMessage is the message of every 500, the details of the error
+// only go to the server logs.
+const internalErrorMessage = "internal server error, see the server logs with the request ID"
+
+func newAPIError(status int, message string, details interface{}) *APIError {
+	code, ok := errorCodes[status]
+	if !ok {
+		code = "error"
+	}
+	return &APIError{Status: status, Code: code, Message: message, Details: details}
+}
+
+func badRequest(format string, args ...interface{}) *APIError {
+	return newAPIError(http.StatusBadRequest, fmt.Sprintf(format, args...), nil)
+}
+
+// invalidParam is the 400 of a parameter that doesn't parse, the details
+// name it.
+func invalidParam(name, value, message string) *APIError {
+	return newAPIError(http.StatusBadRequest, message, map[string]string{
+		"parameter": name,
+		"value":     value,
+	})
+}
+
+// apiError maps err to the error written to the client. An *APIError is kept
+// as is, the errors of the repositories and services get the status of their
+// kind and anything else is a 500. The message of a 500 is generic, the
+// driver and SQL details of err only go to the server logs.
+func apiError(err error) *APIError {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, repositories.ErrNotFound), errors.Is(err, services.ErrSnapshotNotFound):
+		return newAPIError(http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, repositories.ErrInvalidCursor):
+		return newAPIError(http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidConfirmation):
+		return newAPIError(http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidImport):
+		return newAPIError(http.StatusUnprocessableEntity, err.Error(), nil)
+	case errors.Is(err, repositories.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		apiErr = newAPIError(http.StatusServiceUnavailable, "the database took too long to answer, try again later", nil)
+		apiErr.Code = "timeout"
+		return apiErr
+	case errors.Is(err, repositories.ErrUnavailable):
+		return newAPIError(http.StatusServiceUnavailable, "the database is unavailable, try again later", nil)
+	default:
+		return newAPIError(http.StatusInternalServerError, internalErrorMessage, nil)
+	}
+}
+
+// writeError writes err in the error envelope, tagged with the request ID
+// also found in the server logs.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := apiError(err)
+	requestID := middleware.GetReqID(r.Context())
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s failed: %v", requestID, r.Method, r.URL.Path, err)
+	}
+	if apiErr.Status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "5")
+	}
+	if requestID != "" {
+		w.Header().Set("X-Request-Id", requestID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{Error: dto.Error{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: requestID,
+	}})
+}
+
+// NotFound and MethodNotAllowed answer the requests no route matches.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, newAPIError(http.StatusNotFound, fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path), nil))
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, newAPIError(http.StatusMethodNotAllowed, fmt.Sprintf("%s isn't allowed on %s", r.Method, r.URL.Path), nil))
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"backend/services"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// acceptFormats maps the media types of an Accept header to export formats.
+var acceptFormats = map[string]string{
+	"text/csv":             services.ExportCSV,
+	"text/*":               services.ExportCSV,
+	"*/*":                  services.ExportCSV,
+	"application/x-ndjson": services.ExportNDJSON,
+	"application/ndjson":   services.ExportNDJSON,
+	"application/jsonl":    services.ExportNDJSON,
+	services.ExportContentTypes[services.ExportXLSX]: services.ExportXLSX,
+}
+
+// ExportStocks streams the stocks matching the filters of GetStocks as a
+// file, newest first. The format, csv, ndjson or xlsx, comes from ?format=
+// or else from the Accept header.
+func (h *StockHandler) ExportStocks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/stocks/export")
+
+	filter, err := stockFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(filter.Sort) > 0 {
+		writeError(w, r, badRequest("exports are ordered by time, newest first, ?sort= isn't available"))
+		return
+	}
+	format, err := exportFormat(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", services.ExportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="stocks-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+
+	out := &exportWriter{ResponseWriter: w}
+	rows, err := h.exports.Export(r.Context(), filter, format, out)
+	if err != nil {
+		if !out.wrote {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, fmt.Errorf("failed to export: %w", err))
+			return
+		}
+		// the 200 is gone already, cutting the connection is how the client
+		// learns the file is incomplete
+		log.Printf("Export failed after %d stocks: %v", rows, err)
+		panic(http.ErrAbortHandler)
+	}
+	fmt.Println("exported", rows, "stocks as", format)
+}
+
+// exportFormat reads ?format=, or picks the format the Accept header
+// prefers, csv when there is no header.
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := services.ExportContentTypes[format]; !ok {
+			return "", invalidParam("format", format, "unsupported format, expected csv, ndjson or xlsx")
+		}
+		return format, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return services.ExportCSV, nil
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			q, _ = strconv.ParseFloat(value, 64)
+		}
+		if format, ok := acceptFormats[mediaType]; ok && q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	if best == "" {
+		return "", newAPIError(http.StatusNotAcceptable, "can't export as "+accept+", accept text/csv, application/x-ndjson or "+
+			services.ExportContentTypes[services.ExportXLSX]+", or use ?format=", nil)
+	}
+	return best, nil
+}
+
+// exportWriter remembers whether the body was started, until then a failed
+// export can still answer with an error.
+type exportWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *exportWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		flusher.Flush()
+	}
+}
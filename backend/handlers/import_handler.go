@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"backend/api"
+	"backend/services"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ImportHandler serves the uploads of rating dumps.
+type ImportHandler struct {
+	imports *services.ImportService
+}
+
+func NewImportHandler(imports *services.ImportService) *ImportHandler {
+	return &ImportHandler{imports: imports}
+}
+
+// ImportRatings takes a multipart upload with the dump in the "file" field.
+// The format comes from ?format= (csv or ndjson) or the file extension, the
+// rows are tagged with ?source=, "import" by default.
+func (h *ImportHandler) ImportRatings(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/import")
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, badRequest("expected a multipart/form-data upload: %v", err))
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeError(w, r, badRequest("the upload has no file field"))
+			return
+		}
+		if err != nil {
+			writeError(w, r, badRequest("failed to read upload: %v", err))
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = api.FormatFromPath(part.FileName())
+		}
+		if format != api.FormatCSV && format != api.FormatNDJSON {
+			writeError(w, r, invalidParam("format", format, "unsupported format, use ?format=csv or ?format=ndjson"))
+			return
+		}
+
+		source := r.URL.Query().Get("source")
+		if source == "" {
+			source = "import"
+		}
+
+		report, err := h.imports.ImportRatings(r.Context(), part, format, source)
+		if err != nil {
+			// the rows stored before the failure stay, the report counts them
+			apiErr := apiError(err)
+			switch apiErr.Status {
+			case http.StatusInternalServerError:
+				log.Printf("[%s] import failed: %v", middleware.GetReqID(r.Context()), err)
+				apiErr = newAPIError(http.StatusUnprocessableEntity, "failed to store the rows, the ones counted in the report were stored", report)
+			case http.StatusUnprocessableEntity:
+				apiErr = newAPIError(http.StatusUnprocessableEntity, apiErr.Message, report)
+			}
+			writeError(w, r, apiErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"backend/dto"
+	"backend/models"
+	"backend/repositories"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageRequest is how a list is paged. Offset mode reads ?page= and
+// ?page_size= (or ?pageSize=, which the frontend sends). A ?cursor=
+// parameter, empty for the first page, switches to keyset mode, where
+// ?total=true adds a total capped for speed.
+type pageRequest struct {
+	Page       int
+	PageSize   int
+	CursorMode bool
+	Cursor     string
+	WithTotal  bool
+}
+
+func readPageRequest(r *http.Request) pageRequest {
+	q := r.URL.Query()
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(q.Get("page_size"))
+	if err != nil {
+		pageSize, _ = strconv.Atoi(q.Get("pageSize"))
+	}
+	switch {
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	case pageSize <= 0:
+		pageSize = defaultPageSize
+	}
+
+	withTotal, _ := strconv.ParseBool(q.Get("total"))
+	return pageRequest{
+		Page:       page,
+		PageSize:   pageSize,
+		CursorMode: q.Has("cursor"),
+		Cursor:     q.Get("cursor"),
+		WithTotal:  withTotal,
+	}
+}
+
+// listStocks writes the page of stocks matching filter, in the mode asked by
+// the request.
+func (h *StockHandler) listStocks(w http.ResponseWriter, r *http.Request, filter repositories.StockFilter) {
+	req := readPageRequest(r)
+
+	if !req.CursorMode {
+		items, page, _, totalItems, err := h.repo.Find(r.Context(), filter, req.Page, req.PageSize)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+			return
+		}
+		h.writeOffsetPage(w, items, page, req.PageSize, totalItems)
+		return
+	}
+
+	if len(filter.Sort) > 0 {
+		writeError(w, r, badRequest("sorting isn't available with cursor pagination, the cursor orders by time"))
+		return
+	}
+	page, err := h.repo.FindCursor(r.Context(), filter, req.Cursor, req.PageSize, req.WithTotal)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		writeError(w, r, invalidParam("cursor", req.Cursor, err.Error()))
+		return
+	}
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+		return
+	}
+
+	if h.v2 {
+		pagination := dto.CursorPagination(req.PageSize, page.NextCursor, page.PrevCursor)
+		if req.WithTotal {
+			pagination = pagination.WithTotal(page.Total, page.Approximate)
+		}
+		writeJSON(w, dto.StockPage{Items: dto.NewStocks(page.Items), Pagination: pagination})
+		return
+	}
+
+	pagination := map[string]interface{}{
+		"pageSize":    req.PageSize,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+	}
+	if req.WithTotal {
+		pagination["totalItems"] = page.Total
+		pagination["approximate"] = page.Approximate
+	}
+
+	resp := map[string]interface{}{
+		"items":      page.Items,
+		"pagination": pagination,
+	}
+	writeJSON(w, resp)
+}
+
+func (h *StockHandler) writeOffsetPage(w http.ResponseWriter, items []models.Stock, page, pageSize, totalItems int) {
+	if h.v2 {
+		writeJSON(w, dto.StockPage{
+			Items:      dto.NewStocks(items),
+			Pagination: dto.OffsetPagination(page, pageSize, totalItems),
+		})
+		return
+	}
+
+	totalPages := totalItems / pageSize
+	if totalItems%pageSize != 0 {
+		totalPages += 1
+	}
+
+	resp := map[string]interface{}{
+		"items": items,
+		"pagination": map[string]interface{}{
+			"page":       page,
+			"pageSize":   pageSize,
+			"totalItems": totalItems,
+			"totalPages": totalPages,
+		},
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// requiredParam answers 400 and returns false when value is empty.
+func requiredParam(w http.ResponseWriter, r *http.Request, name, value string) bool {
+	if value == "" {
+		writeError(w, r, newAPIError(http.StatusBadRequest, fmt.Sprintf("%s parameter is required", name), map[string]string{
+			"parameter": name,
+		}))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"backend/repositories"
+	"backend/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// QuarantineHandler serves the rows the syncs quarantined.
+type QuarantineHandler struct {
+	repo    *repositories.QuarantineRepository
+	service *services.QuarantineService
+}
+
+func NewQuarantineHandler(repo *repositories.QuarantineRepository, service *services.QuarantineService) *QuarantineHandler {
+	return &QuarantineHandler{repo: repo, service: service}
+}
+
+func (h *QuarantineHandler) GetQuarantine(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/quarantine")
+
+	q := r.URL.Query()
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+	switch {
+	case pageSize > 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 20
+	}
+
+	filter, err := quarantineFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	items, totalItems, err := h.repo.GetQuarantined(r.Context(), filter, page, pageSize)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+		return
+	}
+
+	totalPages := totalItems / pageSize
+	if totalItems%pageSize != 0 {
+		totalPages += 1
+	}
+
+	resp := map[string]interface{}{
+		"items": items,
+		"pagination": map[string]interface{}{
+			"page":       page,
+			"pageSize":   pageSize,
+			"totalItems": totalItems,
+			"totalPages": totalPages,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *QuarantineHandler) ReprocessQuarantine(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/quarantine/reprocess")
+
+	filter, err := quarantineFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	result, err := h.service.Reprocess(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to reprocess: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *QuarantineHandler) PurgeQuarantine(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for DELETE /api/quarantine")
+
+	filter, err := quarantineFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deleted, err := h.repo.DeleteQuarantined(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to purge: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message": "quarantined rows purged",
+		"deleted": deleted,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// quarantineFilter reads ?id= (repeatable), ?job_id= and ?before= (RFC3339).
+func quarantineFilter(r *http.Request) (repositories.QuarantineFilter, error) {
+	q := r.URL.Query()
+	var filter repositories.QuarantineFilter
+
+	for _, value := range q["id"] {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, invalidParam("id", value, "invalid id, expected a number")
+		}
+		filter.IDs = append(filter.IDs, uint(id))
+	}
+
+	if value := q.Get("job_id"); value != "" {
+		jobID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, invalidParam("job_id", value, "invalid job_id, expected a number")
+		}
+		filter.JobID = uint(jobID)
+	}
+
+	if value := q.Get("before"); value != "" {
+		before, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, invalidParam("before", value, "invalid before, expected RFC3339")
+		}
+		filter.Before = before
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"backend/repositories"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RevisionHandler serves the revisions the version conflict policy kept.
+type RevisionHandler struct {
+	repo *repositories.RevisionRepository
+}
+
+func NewRevisionHandler(repo *repositories.RevisionRepository) *RevisionHandler {
+	return &RevisionHandler{repo: repo}
+}
+
+func (h *RevisionHandler) GetStockRevisions(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/stocks/ticker/{ticker}/revisions")
+
+	q := r.URL.Query()
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+	switch {
+	case pageSize > 100:
+		pageSize = 100
+	case pageSize <= 0:
+		pageSize = 20
+	}
+
+	ticker := chi.URLParam(r, "ticker")
+	if !requiredParam(w, r, "ticker", ticker) {
+		return
+	}
+
+	items, totalItems, err := h.repo.GetRevisions(r.Context(), ticker, page, pageSize)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+		return
+	}
+
+	totalPages := totalItems / pageSize
+	if totalItems%pageSize != 0 {
+		totalPages += 1
+	}
+
+	resp := map[string]interface{}{
+		"items": items,
+		"pagination": map[string]interface{}{
+			"page":       page,
+			"pageSize":   pageSize,
+			"totalItems": totalItems,
+			"totalPages": totalPages,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
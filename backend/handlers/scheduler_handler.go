@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func (h *SyncHandler) GetSyncSchedule(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync/schedule")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scheduler.Status())
+}
+
+func (h *SyncHandler) PauseSyncSchedule(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync/schedule/pause")
+
+	if h.scheduler == nil {
+		writeError(w, r, newAPIError(http.StatusConflict, "sync scheduler is not enabled", nil))
+		return
+	}
+	h.scheduler.Pause()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scheduler.Status())
+}
+
+func (h *SyncHandler) ResumeSyncSchedule(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync/schedule/resume")
+
+	if h.scheduler == nil {
+		writeError(w, r, newAPIError(http.StatusConflict, "sync scheduler is not enabled", nil))
+		return
+	}
+	h.scheduler.Resume()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scheduler.Status())
+}
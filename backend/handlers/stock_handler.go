@@ -1,479 +1,358 @@
 package handlers
 
 import (
-	"backend/api"
-	"backend/db"
+	"backend/dto"
 	"backend/repositories"
 	"backend/services"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func FetchAndStoreStock(w http.ResponseWriter, r *http.Request){
-	fmt.Println("received request for /api/sync")
-	total, err := api.FetchData()
-	if err != nil{
-		http.Error(w, "failed to fetch data: "+ err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	resp := map[string]interface{}{
-		"message": "data fetched and stored",
-		"total_items": total,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+// StockHandler serves the stock queries out of a StockRepository.
+type StockHandler struct {
+	repo            repositories.StockRepository
+	recommendations *services.RecommendationService
+	exports         *services.ExportService
+	v2              bool // answers with the dto bodies of /api/v2
 }
 
-
-func GetAllStoreData(w http.ResponseWriter, r *http.Request){	
-
-	q := r.URL.Query()
-
-	page, _ := strconv.Atoi(q.Get("page"))
-    if page <= 0 {
-      page = 1
-    }
-
-    pageSize, _ := strconv.Atoi(q.Get("page_size"))
-    switch {
-    case pageSize > 100:
-      pageSize = 100
-    case pageSize <= 0:
-      pageSize = 20
-    }
-
-	items, newpage, newpageSize, totalItems, err := repositories.GetAll(page, pageSize)
-	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": (totalItems/pageSize),
-
-		},
+func NewStockHandler(repo repositories.StockRepository) *StockHandler {
+	return &StockHandler{
+		repo:            repo,
+		recommendations: services.NewRecommendationService(repo),
+		exports:         services.NewExportService(repo),
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+}
 
-	fmt.Println(r.Body)
+// V2 returns a handler running the same queries that answers with the typed
+// snake_case bodies of /api/v2.
+func (h *StockHandler) V2() *StockHandler {
+	v2 := *h
+	v2.v2 = true
+	return &v2
 }
 
-func DeleteTable(w http.ResponseWriter, r *http.Request){
-	fmt.Println("received request for /api/sync/del")
+func (h *StockHandler) GetAllStoreData(w http.ResponseWriter, r *http.Request){
+	fmt.Println("received request for /api/stocks/all")
 
-	if err := db.Drop(); err != nil{
-		http.Error(w, "failed to delete de table: "+err.Error(), http.StatusInternalServerError)
+	sort, err := stockSort(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	resp := map[string]interface {}{
-		"message": "table has been deleted",
-		"data": "ok",
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{Sort: sort})
 }
 
-func GetStoreByTicker(w http.ResponseWriter, r *http.Request){
+func (h *StockHandler) GetStoreByTicker(w http.ResponseWriter, r *http.Request){
 	fmt.Println("received request for /api/stocks/ticker")
 
-	q := r.URL.Query()
-
-	page, _ := strconv.Atoi(q.Get("page"))
-    if page <= 0 {
-      page = 1
-    }
-
-    pageSize, _ := strconv.Atoi(q.Get("page_size"))
-    switch {
-    case pageSize > 100:
-      pageSize = 100
-    case pageSize <= 0:
-      pageSize = 20
-    }
-
 	ticker := chi.URLParam(r, "ticker")
-	if ticker == "" {
-		http.Error(w, "ticker parameter is required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "ticker", ticker) {
+		return
 	}
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByTicker(ticker, page, pageSize)
+	sort, err := stockSort(r)
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
-
-	if totalItems%pageSize != 0 {
-		totalPages += 1
-	}
-
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{Ticker: ticker, Sort: sort})
 }
 
-
-func GetStoreByCompany(w http.ResponseWriter, r *http.Request){
+func (h *StockHandler) GetStoreByCompany(w http.ResponseWriter, r *http.Request){
 	fmt.Println("received request for /api/stocks/company")
 
-	q := r.URL.Query()
-
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-	  page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	switch {
-	case pageSize > 100:
-	  pageSize = 100
-	case pageSize <= 0:
-	  pageSize = 20
-	}
-
 	company := chi.URLParam(r, "company")
-	if company == "" {
-		http.Error(w, "company parameter is required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "company", company) {
+		return
 	}
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByCompany(company, page, pageSize)
+	sort, err := stockSort(r)
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
-
-	if totalItems%pageSize != 0 {
-		totalPages += 1
-	}
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{Company: company, Sort: sort})
 }
-/* 
-r.Get("/api/stocks/brokerage/{brokerage}", handlers.GetStoreByBrokerage)
-r.Get("/api/stocks/action/{action}", handlers.GetStoreByAction)
-r.Get("/api/stocks/rating-to/{rating}", handlers.GetStoreByRatingTo)
-r.Get("/api/stocks/rating-from/{rating}", handlers.GetStoreByRatingFrom)
-r.Get("/api/stocks/price-to/{price}", handlers.GetStoreByPriceTo) */
-
-func GetStoreByBrokerage(w http.ResponseWriter, r *http.Request){
-	fmt.Println("received request for /api/stocks/brokerage")
-
-	q := r.URL.Query()
 
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-	  page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	switch {
-	case pageSize > 100:
-	  pageSize = 100
-	case pageSize <= 0:
-	  pageSize = 20
-	}
+func (h *StockHandler) GetStoreByBrokerage(w http.ResponseWriter, r *http.Request){
+	fmt.Println("received request for /api/stocks/brokerage")
 
 	brokerage := chi.URLParam(r, "brokerage")
-	if brokerage == "" {
-		http.Error(w, "brokerage parameter is required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "brokerage", brokerage) {
+		return
 	}
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByBrokerage(brokerage, page, pageSize)
+	sort, err := stockSort(r)
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
-
-	if totalItems%pageSize != 0 {
-		totalPages += 1
-	}
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{Brokerage: brokerage, Sort: sort})
 }
 
-func GetStoreByAction(w http.ResponseWriter, r *http.Request){
+func (h *StockHandler) GetStoreByAction(w http.ResponseWriter, r *http.Request){
 	fmt.Println("received request for /api/stocks/action")
 
-	q := r.URL.Query()
-
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-	  page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	switch {
-	case pageSize > 100:
-	  pageSize = 100
-	case pageSize <= 0:
-	  pageSize = 20
-	}
-
 	action := chi.URLParam(r, "action")
-	if action == "" {
-		http.Error(w, "action parameter is required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "action", action) {
+		return
 	}
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByAction(action, page, pageSize)
+	sort, err := stockSort(r)
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
-
-	if totalItems%pageSize != 0 {
-		totalPages += 1
-	}
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{Action: action, Sort: sort})
 }
 
-func GetStoreByRatingTo(w http.ResponseWriter, r *http.Request){
+func (h *StockHandler) GetStoreByRatingTo(w http.ResponseWriter, r *http.Request){
 	fmt.Println("received request for /api/stocks/rating-to")
 
-	q := r.URL.Query()
-
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-	  page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	switch {
-	case pageSize > 100:
-	  pageSize = 100
-	case pageSize <= 0:
-	  pageSize = 20
-	}
-
 	rating := chi.URLParam(r, "rating")
-	if rating == "" {
-		http.Error(w, "rating parameter is required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "rating", rating) {
+		return
 	}
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByRatingTo(rating, page, pageSize)
+	sort, err := stockSort(r)
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
-
-	if totalItems%pageSize != 0 {
-		totalPages += 1
-	}
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{RatingTo: rating, Sort: sort})
 }
 
-func GetStoreByRatingFrom(w http.ResponseWriter, r *http.Request){
+func (h *StockHandler) GetStoreByRatingFrom(w http.ResponseWriter, r *http.Request){
 	fmt.Println("received request for /api/stocks/rating-from")
 
-	q := r.URL.Query()
-
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-	  page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	switch {
-	case pageSize > 100:
-	  pageSize = 100
-	case pageSize <= 0:
-	  pageSize = 20
-	}
-
 	rating := chi.URLParam(r, "rating")
-	if rating == "" {
-		http.Error(w, "rating parameter is required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "rating", rating) {
+		return
 	}
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByRatingFrom(rating, page, pageSize)
+	sort, err := stockSort(r)
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
-
-	if totalItems%pageSize != 0 {
-		totalPages += 1
-	}
-
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
-
-	fmt.Println(r.Body)
+	h.listStocks(w, r, repositories.StockFilter{RatingFrom: rating, Sort: sort})
 }
 
-func GetStoreByPrice(w http.ResponseWriter, r *http.Request){
+func (h *StockHandler) GetStoreByPrice(w http.ResponseWriter, r *http.Request){
 	fmt.Println("received request for /api/stocks/price-range")
 
-	q := r.URL.Query()
-
 	minPrice := chi.URLParam(r, "min")
 	maxPrice := chi.URLParam(r, "max")
-	if minPrice == "" || maxPrice == "" {
-		http.Error(w, "min and max price parameters are required",  http.StatusInternalServerError)
+	if !requiredParam(w, r, "min", minPrice) || !requiredParam(w, r, "max", maxPrice) {
 		return
 	}
 	min, err := strconv.ParseFloat(minPrice, 64)
 	if err != nil {
-		http.Error(w, "invalid min price: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, invalidParam("min", minPrice, "invalid min price, expected a number"))
 		return
 	}
 	max, err := strconv.ParseFloat(maxPrice, 64)
 	if err != nil {
-		http.Error(w, "invalid max price: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, invalidParam("max", maxPrice, "invalid max price, expected a number"))
 		return
 	}
-
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-	  page = 1
+	if min > max {
+		writeError(w, r, newAPIError(http.StatusUnprocessableEntity, "min price is greater than max price", map[string]float64{
+			"min": min,
+			"max": max,
+		}))
+		return
 	}
 
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	switch {
-	case pageSize > 100:
-	  pageSize = 100
-	case pageSize <= 0:
-	  pageSize = 20
+	sort, err := stockSort(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
+	h.listStocks(w, r, repositories.StockFilter{TargetMin: &min, TargetMax: &max, Sort: sort})
+}
+
+//recommendations always return 5 items and these are not paginated
+func (h *StockHandler) GetStoreByRecommendation(w http.ResponseWriter, r *http.Request){
+	fmt.Println("received request for /api/recommendations")
 
-	items, newpage, newpageSize, totalItems, err := repositories.GetByPrice(min, max, page, pageSize)
+	items, err := h.recommendations.GetRecommendations(r.Context())
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
 		return
 	}
 
-	totalPages := float64(totalItems/pageSize)
+	var resp interface{} = items
+	if h.v2 {
+		resp = dto.NewRecommendationList(items)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 
-	if totalItems%pageSize != 0 {
-		totalPages += 1
+// GetStocks lists the stocks matching any combination of the filters read by
+// stockFilter.
+func (h *StockHandler) GetStocks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/stocks")
+
+	filter, err := stockFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	resp := map[string]interface {}{
-		"items": items,
-		"pagination": map[string]interface{}{
-			"page": newpage,
-			"pageSize": newpageSize,
-			"totalItems": totalItems,
-			"totalPages": totalPages,
-		},
+	h.listStocks(w, r, filter)
+}
+
+// stockFilter reads ?query= (ticker or company), ?ticker=, ?company=,
+// ?brokerage=, ?action=, ?rating_from=, ?rating_to=, ?match= (contains,
+// exact or prefix), ?target_min=, ?target_max=, ?from= and ?to= (RFC3339 or
+// YYYY-MM-DD, a date alone in ?to= includes that whole day) and ?sort=.
+func stockFilter(r *http.Request) (repositories.StockFilter, error) {
+	q := r.URL.Query()
+	filter := repositories.StockFilter{
+		Query:      q.Get("query"),
+		Ticker:     q.Get("ticker"),
+		Company:    q.Get("company"),
+		Brokerage:  q.Get("brokerage"),
+		Action:     q.Get("action"),
+		RatingFrom: q.Get("rating_from"),
+		RatingTo:   q.Get("rating_to"),
+	}
+
+	var err error
+	if filter.Match, err = repositories.ParseMatchMode(q.Get("match")); err != nil {
+		return filter, invalidParam("match", q.Get("match"), err.Error())
+	}
+	if filter.Sort, err = stockSort(r); err != nil {
+		return filter, err
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value **float64
+	}{{"target_min", &filter.TargetMin}, {"target_max", &filter.TargetMax}} {
+		value := q.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		target, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return filter, invalidParam(bound.name, value, fmt.Sprintf("invalid %s, expected a number", bound.name))
+		}
+		*bound.value = &target
+	}
+
+	if value := q.Get("from"); value != "" {
+		if filter.From, _, err = parseFilterTime(value); err != nil {
+			return filter, invalidParam("from", value, "invalid from, expected RFC3339 or YYYY-MM-DD")
+		}
+	}
+	if value := q.Get("to"); value != "" {
+		var dateOnly bool
+		if filter.To, dateOnly, err = parseFilterTime(value); err != nil {
+			return filter, invalidParam("to", value, "invalid to, expected RFC3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			filter.To = filter.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseFilterTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
 
-	fmt.Println(r.Body)
+// stockSort reads ?sort=, a comma separated list of fields with a "-" prefix
+// for descending order, like "-upside,ticker".
+func stockSort(r *http.Request) ([]repositories.StockOrder, error) {
+	value := r.URL.Query().Get("sort")
+	sort, err := repositories.ParseStockSort(value)
+	if err != nil {
+		return nil, invalidParam("sort", value, err.Error())
+	}
+	return sort, nil
 }
 
-//recommendations always return 5 items and these are not paginated
-func GetStoreByRecommendation(w http.ResponseWriter, r *http.Request){
-	fmt.Println("received request for /api/recommendations")
+// GetSortedStocks lists the stocks sorted by the {field} of the path, in
+// ?order= (asc or desc, desc by default for time, targets and upside). The
+// filters of GetStocks apply, ?query= being the search box of the UI.
+func (h *StockHandler) GetSortedStocks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/stocks/sorted")
+
+	q := r.URL.Query()
 
-	items, err := services.GetRecommendationsService()
+	field, err := repositories.ParseSortField(chi.URLParam(r, "field"))
 	if err != nil {
-		http.Error(w, "failed to get data: "+ err.Error(), http.StatusInternalServerError)
+		writeError(w, r, invalidParam("field", chi.URLParam(r, "field"), err.Error()))
 		return
 	}
 
-	resp := items
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	order := repositories.StockOrder{Field: field}
+	switch q.Get("order") {
+	case "":
+		order.Desc = field != "ticker" && field != "company" && field != "brokerage"
+	case "asc":
+	case "desc":
+		order.Desc = true
+	default:
+		writeError(w, r, invalidParam("order", q.Get("order"), "invalid order, expected asc or desc"))
+		return
+	}
+
+	filter, err := stockFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	filter.Sort = append([]repositories.StockOrder{order}, filter.Sort...)
 
-	fmt.Println(r.Body)
+	h.listStocks(w, r, filter)
 }
 
+// SearchStocks ranks the stocks against the {query} of the path, see
+// repositories.Search.
+func (h *StockHandler) SearchStocks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/stocks/search")
+
+	req := readPageRequest(r)
+	if req.CursorMode {
+		writeError(w, r, badRequest("search results are ranked, they page with ?page= instead of a cursor"))
+		return
+	}
+
+	query := strings.TrimSpace(chi.URLParam(r, "query"))
+	if !requiredParam(w, r, "query", query) {
+		return
+	}
+
+	items, newpage, _, totalItems, err := h.repo.Search(r.Context(), query, req.Page, req.PageSize)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+		return
+	}
+
+	h.writeOffsetPage(w, items, newpage, req.PageSize, totalItems)
+}
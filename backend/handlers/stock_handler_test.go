@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// stockRouter serves the stock handler out of a memory repository holding
+// stocks.
+func stockRouter(stocks ...models.Stock) http.Handler {
+	h := NewStockHandler(repositories.NewMemoryStockRepository(stocks...))
+	r := chi.NewRouter()
+	r.Get("/api/stocks", h.GetStocks)
+	r.Get("/api/stocks/ticker/{ticker}", h.GetStoreByTicker)
+	r.Get("/api/stocks/sorted/{field}", h.GetSortedStocks)
+	return r
+}
+
+type stockPage struct {
+	Items      []models.Stock `json:"items"`
+	Pagination struct {
+		TotalItems int `json:"totalItems"`
+		TotalPages int `json:"totalPages"`
+	} `json:"pagination"`
+}
+
+func get(t *testing.T, router http.Handler, target string) (*httptest.ResponseRecorder, stockPage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+	var page stockPage
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("%s: can't decode the page: %v", target, err)
+		}
+	}
+	return rec, page
+}
+
+func TestStockHandlerServesTheRepository(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	router := stockRouter(
+		models.Stock{Ticker: "AAPL", TargetFrom: 100, TargetTo: 150, RatingTo: "Buy", Time: day},
+		models.Stock{Ticker: "AAPL", TargetFrom: 150, TargetTo: 120, RatingTo: "Sell", Time: day.Add(time.Hour)},
+		models.Stock{Ticker: "KO", TargetFrom: 60, TargetTo: 60, RatingTo: "Neutral", Time: day},
+	)
+
+	tests := []struct {
+		target string
+		want   []float64 // TargetTo of the items, in order
+	}{
+		{"/api/stocks/ticker/AAPL", []float64{150, 120}},
+		{"/api/stocks?rating_to=Neutral", []float64{60}},
+		{"/api/stocks/sorted/target_to?order=asc", []float64{60, 120, 150}},
+		{"/api/stocks/sorted/RatingTo?order=desc", []float64{120, 60, 150}},
+		{"/api/stocks?sort=-upside", []float64{150, 60, 120}},
+	}
+
+	for _, test := range tests {
+		rec, page := get(t, router, test.target)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want 200: %s", test.target, rec.Code, rec.Body)
+			continue
+		}
+		var got []float64
+		for _, stock := range page.Items {
+			got = append(got, stock.TargetTo)
+		}
+		if !slices.Equal(got, test.want) || page.Pagination.TotalItems != len(test.want) {
+			t.Errorf("%s: got %v of %d, want %v", test.target, got, page.Pagination.TotalItems, test.want)
+		}
+	}
+}
+
+func TestStockHandlerRejectsInvalidSorts(t *testing.T) {
+	router := stockRouter()
+
+	for _, target := range []string{
+		"/api/stocks/sorted/price",
+		"/api/stocks/sorted/ticker?order=up",
+		"/api/stocks?sort=ticker,price",
+	} {
+		if rec, _ := get(t, router, target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"backend/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SyncHandler serves the syncs and their scheduler. scheduler is nil when
+// it's disabled.
+type SyncHandler struct {
+	syncs     *services.SyncService
+	scheduler *services.Scheduler
+}
+
+func NewSyncHandler(syncs *services.SyncService, scheduler *services.Scheduler) *SyncHandler {
+	return &SyncHandler{syncs: syncs, scheduler: scheduler}
+}
+
+// EnqueueSync queues a sync and answers right away. GET /api/sync is served
+// by it too, a request held open for the whole crawl would time out.
+func (h *SyncHandler) EnqueueSync(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync")
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		h.DryRunSync(w, r)
+		return
+	}
+
+	restart, _ := strconv.ParseBool(r.URL.Query().Get("restart"))
+	job, created, err := h.syncs.StartSync(restart)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to enqueue sync: %w", err))
+		return
+	}
+
+	message := "sync job queued"
+	if !created {
+		message = "a sync job is already running"
+	}
+
+	resp := map[string]interface{}{
+		"message": message,
+		"job":     job,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/sync/jobs/%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DryRunSync crawls the sources and reports what a sync would change. It
+// runs in the request, a dry run never writes so it doesn't need a job.
+func (h *SyncHandler) DryRunSync(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync?dry_run=true")
+
+	report, err := h.syncs.DryRunSync(r.Context())
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to preview sync: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message": "dry run, nothing was written",
+		"report":  report,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *SyncHandler) GetSyncJob(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync/jobs/{id}")
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, r, invalidParam("id", chi.URLParam(r, "id"), "invalid job id, expected a number"))
+		return
+	}
+
+	job, err := h.syncs.GetSyncJob(r.Context(), uint(id))
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *SyncHandler) ListSyncJobs(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync/jobs")
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	switch {
+	case limit > 100:
+		limit = 100
+	case limit <= 0:
+		limit = 20
+	}
+
+	jobs, err := h.syncs.ListSyncJobs(r.Context(), limit)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to get data: %w", err))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"items": jobs,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// StreamSyncEvents streams the progress of syncs as Server-Sent Events. A
+// client connecting mid-sync first receives a "status" event with the job.
+func (h *SyncHandler) StreamSyncEvents(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/sync/events")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.New("streaming not supported"))
+		return
+	}
+
+	events, unsubscribe := services.SubscribeSyncEvents()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if job, running := h.syncs.CurrentSyncJob(); running {
+		writeEvent(w, "status", job)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			writeEvent(w, event.Type, event)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, name string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+}
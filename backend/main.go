@@ -3,17 +3,95 @@ package main
 import (
 	"backend/routes"
 	"backend/config"
+	"backend/db"
+	"backend/handlers"
+	"backend/openapi"
+	"backend/repositories"
+	"backend/services"
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 15 * time.Second
+
 func main(){
 	config.LoadEnv()
-	r := routes.StockRoutes()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := db.Init(config.LoadDB())
+	if err != nil {
+		log.Fatalf("Failed to open the database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Println("Can't close the database:", err)
+		}
+	}()
+
+	if _, err := repositories.ParseConflictPolicy(config.LoadPipeline().ConflictPolicy); err != nil {
+		log.Fatalf("Invalid SYNC_CONFLICT_POLICY: %v", err)
+	}
+	repos := repositories.NewRepositories(conn)
+	if err := repos.Sync.FailUnfinishedSyncJobs(ctx); err != nil {
+		log.Println("Can't clean up unfinished sync jobs:", err)
+	}
+	syncs := services.NewSyncService(ctx, repos)
+	scheduler, err := services.StartScheduler(ctx, config.LoadSchedule(), syncs)
+	if err != nil {
+		log.Fatalf("Failed to start the sync scheduler: %v", err)
+	}
+	archive := services.NewArchiveService(repos.Archive, repos.Stocks, config.LoadArchive())
+	archive.StartRetention(ctx)
+	// runs before the database is closed: the background work stops with ctx
+	// and must be done with the database first
+	defer func() {
+		stop()
+		log.Println("Waiting for the sync, the scheduler and the retention to stop")
+		scheduler.Wait()
+		syncs.Wait()
+		archive.Wait()
+	}()
+	r := routes.StockRoutes(routes.Handlers{
+		Stocks:     handlers.NewStockHandler(repos.Stocks),
+		Revisions:  handlers.NewRevisionHandler(repos.Revisions),
+		Sync:       handlers.NewSyncHandler(syncs, scheduler),
+		Import:     handlers.NewImportHandler(services.NewImportService(repos.Stocks)),
+		Quarantine: handlers.NewQuarantineHandler(repos.Quarantine, services.NewQuarantineService(repos.Quarantine, repos.Stocks)),
+		Admin:      handlers.NewAdminHandler(archive),
+	})
+	if undescribed, _, err := openapi.Check(r); err == nil && len(undescribed) > 0 {
+		log.Println("Routes missing from the OpenAPI document:", undescribed)
+	}
 	port := config.LoadPort()
 
-	log.Println("Server running in: http://localhost:"+port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal("Failed to start the server: %v", err)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Server running in: http://localhost:"+port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Failed to start the server: %v", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, waiting for the requests in flight")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Can't shut down the server cleanly:", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The tables as AutoMigrate used to create them. Up is AutoMigrate on these
+// snapshots, so databases created before the migrations existed are adopted
+// as they are.
+
+type stock0001 struct {
+	Ticker     string `gorm:"primaryKey"`
+	TargetFrom float64
+	TargetTo   float64
+	Company    string
+	Action     string
+	Brokerage  string
+	RatingFrom string
+	RatingTo   string
+	Time       time.Time `gorm:"primaryKey"`
+	Source     string
+}
+
+func (stock0001) TableName() string { return "stocks" }
+
+type syncCheckpoint0001 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Source    string `gorm:"uniqueIndex"`
+	NextPage  string
+	Batches   int
+	Completed bool
+	UpdatedAt time.Time
+}
+
+func (syncCheckpoint0001) TableName() string { return "sync_checkpoints" }
+
+type syncJob0001 struct {
+	ID           uint `gorm:"primaryKey"`
+	State        string
+	Restart      bool
+	PagesFetched int
+	Inserted     int
+	Updated      int
+	Ignored      int
+	Rejected     int
+	Failed       int
+	FetchMs      int64
+	ConvertMs    int64
+	WriteMs      int64
+	StalledMs    int64
+	Error        string
+	CreatedAt    time.Time
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+	DurationMs   int64
+}
+
+func (syncJob0001) TableName() string { return "sync_jobs" }
+
+type quarantinedStock0001 struct {
+	ID        uint `gorm:"primaryKey"`
+	Payload   string
+	Error     string
+	Source    string
+	Cursor    string
+	JobID     uint `gorm:"index:idx_quarantined_stocks_job_id"`
+	CreatedAt time.Time
+}
+
+func (quarantinedStock0001) TableName() string { return "quarantined_stocks" }
+
+type stockValues0001 struct {
+	TargetFrom float64
+	TargetTo   float64
+	Company    string
+	Action     string
+	Brokerage  string
+	RatingFrom string
+	RatingTo   string
+}
+
+type stockRevision0001 struct {
+	ID        uint      `gorm:"primaryKey"`
+	Ticker    string    `gorm:"index:idx_revision_key"`
+	Time      time.Time `gorm:"index:idx_revision_key"`
+	Fields    string
+	Previous  stockValues0001 `gorm:"embedded;embeddedPrefix:previous_"`
+	New       stockValues0001 `gorm:"embedded;embeddedPrefix:new_"`
+	Source    string
+	JobID     uint `gorm:"index:idx_stock_revisions_job_id"`
+	CreatedAt time.Time
+}
+
+func (stockRevision0001) TableName() string { return "stock_revisions" }
+
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&stock0001{},
+			&syncCheckpoint0001{},
+			&syncJob0001{},
+			&quarantinedStock0001{},
+			&stockRevision0001{},
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(
+			&stockRevision0001{},
+			&quarantinedStock0001{},
+			&syncJob0001{},
+			&syncCheckpoint0001{},
+			&stock0001{},
+		)
+	},
+}
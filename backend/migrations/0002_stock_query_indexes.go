@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// stockQueryColumns are the columns the GetBy* queries filter or order on.
+var stockQueryColumns = []string{"company", "brokerage", "rating_to", "action", "time", "target_to"}
+
+var stockQueryIndexes = Migration{
+	Version: 2,
+	Name:    "stock_query_indexes",
+	Up: func(tx *gorm.DB) error {
+		for _, column := range stockQueryColumns {
+			if err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_stocks_%s ON stocks (%s)", column, column)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, column := range stockQueryColumns {
+			if err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS idx_stocks_%s", column)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type archivedStock0003 struct {
+	Ticker     string `gorm:"primaryKey"`
+	TargetFrom float64
+	TargetTo   float64
+	Company    string
+	Action     string
+	Brokerage  string
+	RatingFrom string
+	RatingTo   string
+	Time       time.Time `gorm:"primaryKey"`
+	Source     string
+	ArchivedAt time.Time
+}
+
+func (archivedStock0003) TableName() string { return "archived_stocks" }
+
+var archivedStocks = Migration{
+	Version: 3,
+	Name:    "archived_stocks",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&archivedStock0003{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&archivedStock0003{})
+	},
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// stockSearchColumns are matched by trigram similarity in the stock search.
+var stockSearchColumns = []string{"ticker", "company", "brokerage"}
+
+// stockSearchIndexes adds GIN trigram indexes, used by both the similarity
+// operator and ILIKE '%x%'. SQLite has neither, its search scans the table.
+var stockSearchIndexes = Migration{
+	Version: 4,
+	Name:    "stock_search_indexes",
+	Up: func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "postgres" {
+			return nil
+		}
+		if err := tx.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+			return err
+		}
+		for _, column := range stockSearchColumns {
+			if err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_stocks_%s_trgm ON stocks USING GIN (%s gin_trgm_ops)", column, column)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "postgres" {
+			return nil
+		}
+		for _, column := range stockSearchColumns {
+			if err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS idx_stocks_%s_trgm", column)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// stockKeysetIndex backs the cursor pagination on (time, ticker). It makes
+// idx_stocks_time, its prefix, redundant.
+var stockKeysetIndex = Migration{
+	Version: 5,
+	Name:    "stock_keyset_index",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_stocks_time_ticker ON stocks (time, ticker)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("DROP INDEX IF EXISTS idx_stocks_time").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_stocks_time ON stocks (time)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("DROP INDEX IF EXISTS idx_stocks_time_ticker").Error
+	},
+}
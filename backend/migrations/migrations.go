@@ -0,0 +1,166 @@
+// Package migrations holds the versioned schema changes of the database and
+// the runner that applies and rolls them back, recording each applied
+// version in the schema_migrations table.
+//
+// Migrations never use the structs of package models, which keep changing,
+// but snapshots of them as they were when the migration was written.
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row of schema_migrations, one per applied version.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// all is every migration, in version order.
+var all = []Migration{
+	initialSchema,
+	stockQueryIndexes,
+	archivedStocks,
+	stockSearchIndexes,
+	stockKeysetIndex,
+}
+
+func All() []Migration {
+	return append([]Migration(nil), all...)
+}
+
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+func applied(DB *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := DB.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("can't create schema_migrations: %v", err)
+	}
+	var rows []SchemaMigration
+	if err := DB.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("can't read schema_migrations: %v", err)
+	}
+	versions := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		versions[row.Version] = row
+	}
+	return versions, nil
+}
+
+// Up applies the pending migrations up to and including target, 0 meaning
+// the latest one, each in its own transaction. It returns the migrations it
+// applied, up to the one that failed.
+func Up(DB *gorm.DB, target int) ([]Migration, error) {
+	if target <= 0 {
+		target = Latest()
+	}
+	versions, err := applied(DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range all {
+		if m.Version > target {
+			break
+		}
+		if _, ok := versions[m.Version]; ok {
+			continue
+		}
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("can't apply migration %d %s: %v", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, each in
+// its own transaction. It returns the migrations it rolled back.
+func Down(DB *gorm.DB, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("can't roll back %d migrations, steps must be at least 1", steps)
+	}
+
+	versions, err := applied(DB)
+	if err != nil {
+		return nil, err
+	}
+
+	rollback := make([]Migration, 0, len(versions))
+	for _, m := range all {
+		if _, ok := versions[m.Version]; ok {
+			rollback = append(rollback, m)
+		}
+	}
+	sort.Slice(rollback, func(i, j int) bool {
+		return rollback[i].Version > rollback[j].Version
+	})
+	if steps < len(rollback) {
+		rollback = rollback[:steps]
+	}
+
+	var done []Migration
+	for _, m := range rollback {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{Version: m.Version}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("can't roll back migration %d %s: %v", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// GetStatus lists every migration and whether it is applied.
+func GetStatus(DB *gorm.DB) ([]Status, error) {
+	versions, err := applied(DB)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(all))
+	for _, m := range all {
+		status := Status{Version: m.Version, Name: m.Name}
+		if row, ok := versions[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ArchivedStock is a rating moved out of the stocks table by the retention
+// policy or an explicit archive, it can be moved back with a restore.
+type ArchivedStock struct {
+	Stock      `gorm:"embedded"`
+	ArchivedAt time.Time
+}
+
+func (ArchivedStock) TableName() string {
+	return "archived_stocks"
+}
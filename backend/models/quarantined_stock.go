@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// QuarantinedStock is an upstream item ConvertStockApi couldn't parse, kept
+// as received so it can be re-processed once the parser is fixed.
+type QuarantinedStock struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Payload   string    `json:"payload"`
+	Error     string    `json:"error"`
+	Source    string    `json:"source"`
+	Cursor    string    `json:"cursor"` // next_page cursor used to request the item's page
+	JobID     uint      `gorm:"index" json:"job_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
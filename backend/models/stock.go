@@ -12,4 +12,42 @@ type Stock struct {
 	RatingFrom string 
 	RatingTo string 
 	Time time.Time `gorm:"primaryKey"`
-}
\ No newline at end of file
+	Source string // name of the api.Source the rating was ingested from
+}
+
+func (Stock) TableName() string {
+	return "stocks"
+}
+
+// Diff lists the fields, besides the (Ticker, Time) key and Source, whose
+// values differ between s and other.
+func (s Stock) Diff(other Stock) []string {
+	var fields []string
+	if s.TargetFrom != other.TargetFrom {
+		fields = append(fields, "target_from")
+	}
+	if s.TargetTo != other.TargetTo {
+		fields = append(fields, "target_to")
+	}
+	if s.Company != other.Company {
+		fields = append(fields, "company")
+	}
+	if s.Action != other.Action {
+		fields = append(fields, "action")
+	}
+	if s.Brokerage != other.Brokerage {
+		fields = append(fields, "brokerage")
+	}
+	if s.RatingFrom != other.RatingFrom {
+		fields = append(fields, "rating_from")
+	}
+	if s.RatingTo != other.RatingTo {
+		fields = append(fields, "rating_to")
+	}
+	return fields
+}
+
+// Key identifies a rating the same way the (ticker, time) primary key does.
+func (s Stock) Key() string {
+	return s.Ticker + "|" + s.Time.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// StockValues are the fields of a rating that an upstream correction can change.
+type StockValues struct {
+	TargetFrom float64 `json:"target_from"`
+	TargetTo   float64 `json:"target_to"`
+	Company    string  `json:"company"`
+	Action     string  `json:"action"`
+	Brokerage  string  `json:"brokerage"`
+	RatingFrom string  `json:"rating_from"`
+	RatingTo   string  `json:"rating_to"`
+}
+
+// StockRevision records a stored rating being replaced by a corrected one.
+type StockRevision struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	Ticker    string      `gorm:"index:idx_revision_key" json:"ticker"`
+	Time      time.Time   `gorm:"index:idx_revision_key" json:"time"`
+	Fields    string      `json:"fields"` // comma separated names of the fields that changed
+	Previous  StockValues `gorm:"embedded;embeddedPrefix:previous_" json:"previous"`
+	New       StockValues `gorm:"embedded;embeddedPrefix:new_" json:"new"`
+	Source    string      `json:"source"`
+	JobID     uint        `gorm:"index" json:"job_id"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func (s Stock) Values() StockValues {
+	return StockValues{
+		TargetFrom: s.TargetFrom,
+		TargetTo:   s.TargetTo,
+		Company:    s.Company,
+		Action:     s.Action,
+		Brokerage:  s.Brokerage,
+		RatingFrom: s.RatingFrom,
+		RatingTo:   s.RatingTo,
+	}
+}
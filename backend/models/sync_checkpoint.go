@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type SyncCheckpoint struct {
+	ID        uint   `gorm:"primaryKey"`
+	Source    string `gorm:"uniqueIndex"` // each source is crawled with its own cursor
+	NextPage  string // cursor of the next page to fetch from the upstream
+	Batches   int    // batches stored since the crawl started
+	Completed bool   // true once the last page has been stored
+	UpdatedAt time.Time
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	SyncJobQueued    = "queued"
+	SyncJobRunning   = "running"
+	SyncJobSucceeded = "succeeded"
+	SyncJobFailed    = "failed"
+)
+
+type SyncJob struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	State        string     `json:"state"`
+	Restart      bool       `json:"restart"`
+	PagesFetched int        `json:"pages_fetched"`
+	Inserted     int        `json:"inserted"`
+	Updated      int        `json:"updated"`
+	Ignored      int        `json:"ignored"`
+	Rejected     int        `json:"rejected"`
+	Failed       int        `json:"failed"`
+	FetchMs      int64      `json:"fetch_ms"`
+	ConvertMs    int64      `json:"convert_ms"`
+	WriteMs      int64      `json:"write_ms"`
+	StalledMs    int64      `json:"stalled_ms"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	DurationMs   int64      `json:"duration_ms"`
+}
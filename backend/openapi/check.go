@@ -0,0 +1,45 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Check compares the document with the routes served by router. It lists
+// the routes the document doesn't describe and the operations it describes
+// that aren't served, both as "METHOD /path".
+func Check(router chi.Routes) (undescribed, unserved []string, err error) {
+	doc := Build()
+
+	served := map[string]bool{}
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		key := method + " " + route
+		served[key] = true
+
+		if _, ok := doc.Paths[route][strings.ToLower(method)]; !ok {
+			undescribed = append(undescribed, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't walk the routes: %v", err)
+	}
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			key := strings.ToUpper(method) + " " + path
+			if !served[key] {
+				unserved = append(unserved, key)
+			}
+		}
+	}
+
+	sort.Strings(undescribed)
+	sort.Strings(unserved)
+	return undescribed, unserved, nil
+}
@@ -0,0 +1,49 @@
+package openapi_test
+
+import (
+	"backend/handlers"
+	"backend/openapi"
+	"backend/repositories"
+	"backend/routes"
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+var schemaRef = regexp.MustCompile(`"#/components/schemas/([^"]+)"`)
+
+// TestCheck fails when a route is added or removed without updating the
+// document. The routes don't touch their handlers until they serve a request,
+// so only the stock handler needs a repository.
+func TestCheck(t *testing.T) {
+	router := routes.StockRoutes(routes.Handlers{
+		Stocks: handlers.NewStockHandler(repositories.NewMemoryStockRepository()),
+	})
+
+	undescribed, unserved, err := openapi.Check(router)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(undescribed) > 0 {
+		t.Errorf("routes missing from the document: %v", undescribed)
+	}
+	if len(unserved) > 0 {
+		t.Errorf("documented routes not served: %v", unserved)
+	}
+}
+
+// TestRefsResolve fails when the document points to a schema it doesn't
+// define.
+func TestRefsResolve(t *testing.T) {
+	document := openapi.JSON()
+	if !json.Valid(document) {
+		t.Fatalf("the document is not valid JSON")
+	}
+
+	schemas := openapi.Build().Components.Schemas
+	for _, match := range schemaRef.FindAllSubmatch(document, -1) {
+		if _, ok := schemas[string(match[1])]; !ok {
+			t.Errorf("%s doesn't resolve", match[0])
+		}
+	}
+}
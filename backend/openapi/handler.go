@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+var (
+	documentOnce sync.Once
+	document     []byte
+)
+
+// JSON returns the document encoded, it is built once.
+func JSON() []byte {
+	documentOnce.Do(func() {
+		document, _ = json.MarshalIndent(Build(), "", "  ")
+	})
+	return document
+}
+
+// Handler serves the document at /api/openapi.json.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/openapi.json")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(JSON())
+}
+
+// DocsHandler serves a page browsing the document, it has no dependency
+// outside of the binary.
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received request for /api/docs")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
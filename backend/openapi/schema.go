@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the document uses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemas builds the schemas of Go types the way encoding/json marshals
+// them, every named struct going to the components under its type name.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// of returns the schema of the type of v.
+func (s *schemas) of(v interface{}) *Schema {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		elem := s.schema(t.Elem())
+		if elem.Ref == "" {
+			elem.Nullable = true
+		}
+		return elem
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name, ok := s.names[t]
+		if !ok {
+			// a type named like one already there goes by its package too,
+			// as dto.Stock does next to models.Stock
+			name = t.Name()
+			if _, taken := s.components[name]; taken {
+				name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+			}
+			s.names[t] = name
+			s.components[name] = nil // placeholder, in case t refers to itself
+			s.components[name] = s.object(t)
+		}
+		return ref(name)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return s.object(t)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	default:
+		// interface{}, anything goes
+		return &Schema{}
+	}
+}
+
+// object lists the fields encoding/json would write, the ones without
+// omitempty being required.
+func (s *schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.fields(t, object)
+	return object
+}
+
+func (s *schemas) fields(t reflect.Type, object *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// untagged embedded structs have their fields promoted
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, object)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		object.Properties[name] = s.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			object.Required = append(object.Required, name)
+		}
+	}
+}
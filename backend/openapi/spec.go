@@ -0,0 +1,546 @@
+package openapi
+
+import (
+	"backend/api"
+	"backend/dto"
+	"backend/models"
+	"backend/repositories"
+	"backend/services"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Version = "1.0.0"
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Tags       []Tag                           `json:"tags"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Deprecated  bool    `json:"deprecated,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description"`
+}
+
+// route is an operation of the document along with where it is served.
+type route struct {
+	Method string
+	Path   string
+	Operation
+}
+
+// Build describes the routes of routes.StockRoutes, the schemas of the
+// bodies being generated from the Go types the handlers encode.
+func Build() Document {
+	s := newSchemas()
+	b := &builder{schemas: s}
+
+	doc := Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "StockApp API",
+			Description: "Analyst ratings synced from the upstream, with search, filters and recommendations.",
+			Version:     Version,
+		},
+		Tags: []Tag{
+			{Name: "stocks", Description: "Stored analyst ratings"},
+			{Name: "recommendations", Description: "Stocks worth a look, scored from their latest ratings"},
+			{Name: "sync", Description: "Ingestion from the upstream sources"},
+			{Name: "import", Description: "Bulk loads of rating dumps"},
+			{Name: "export", Description: "Downloads of the ratings as files"},
+			{Name: "quarantine", Description: "Upstream items that failed to parse"},
+			{Name: "admin", Description: "Data lifecycle operations, they require the admin token"},
+			{Name: "v2", Description: "The stock queries with typed snake_case bodies, computed target changes included"},
+			{Name: "docs", Description: "This document"},
+		},
+		Paths: map[string]map[string]Operation{},
+	}
+
+	v1 := b.routes()
+	for _, r := range append(v1, b.v2(v1)...) {
+		if doc.Paths[r.Path] == nil {
+			doc.Paths[r.Path] = map[string]Operation{}
+		}
+		doc.Paths[r.Path][strings.ToLower(r.Method)] = r.Operation
+	}
+
+	doc.Components = Components{
+		Schemas: s.components,
+		SecuritySchemes: map[string]SecurityScheme{
+			"adminToken": {
+				Type:        "http",
+				Scheme:      "bearer",
+				Description: "The ADMIN_TOKEN of the server",
+			},
+		},
+	}
+	return doc
+}
+
+type builder struct {
+	schemas *schemas
+}
+
+func (b *builder) routes() []route {
+	s := b.schemas
+
+	s.of(dto.ErrorResponse{})
+	s.components["Pagination"] = &Schema{
+		Type: "object",
+		Description: "Offset mode fills page, totalItems and totalPages. Cursor mode, asked with ?cursor=, fills " +
+			"next_cursor and prev_cursor, and totalItems and approximate when ?total=true.",
+		Properties: map[string]*Schema{
+			"page":        {Type: "integer"},
+			"pageSize":    {Type: "integer"},
+			"totalItems":  {Type: "integer"},
+			"totalPages":  {Type: "integer"},
+			"next_cursor": {Type: "string", Description: "Empty on the last page"},
+			"prev_cursor": {Type: "string", Description: "Empty on the first page"},
+			"approximate": {Type: "boolean", Description: "totalItems stopped counting at its cap"},
+		},
+		Required: []string{"pageSize"},
+	}
+	stocks := b.page("StockPage", s.of(models.Stock{}))
+
+	message := func(props map[string]*Schema) *Schema {
+		props["message"] = &Schema{Type: "string"}
+		return object(props)
+	}
+
+	listParams := []Parameter{
+		query("page", integer(1), "Page number, offset mode"),
+		query("page_size", integer(defaultPageSize), "Items per page, at most 100"),
+		{Name: "pageSize", In: "query", Description: "Alias of page_size", Deprecated: true, Schema: &Schema{Type: "integer"}},
+		query("cursor", &Schema{Type: "string"}, "Switches to cursor mode, empty for the first page, then a next_cursor or prev_cursor"),
+		query("total", &Schema{Type: "boolean"}, "Counts the matches in cursor mode"),
+		query("sort", &Schema{Type: "string"}, "Comma separated fields, \"-\" first for descending, like \"-upside,ticker\". Offset mode only. Fields: "+strings.Join(repositories.SortFields(), ", ")),
+	}
+	filterParams := []Parameter{
+		query("query", &Schema{Type: "string"}, "Matches the ticker or the company"),
+		query("ticker", &Schema{Type: "string"}, ""),
+		query("company", &Schema{Type: "string"}, ""),
+		query("brokerage", &Schema{Type: "string"}, ""),
+		query("action", &Schema{Type: "string"}, ""),
+		query("rating_from", &Schema{Type: "string"}, ""),
+		query("rating_to", &Schema{Type: "string"}, ""),
+		query("match", &Schema{Type: "string", Enum: []string{"contains", "exact", "prefix"}, Default: "contains"}, "How the text filters match"),
+		query("target_min", &Schema{Type: "number"}, "Lowest target_to"),
+		query("target_max", &Schema{Type: "number"}, "Highest target_to"),
+		query("from", &Schema{Type: "string"}, "Rated at or after, RFC3339 or YYYY-MM-DD"),
+		query("to", &Schema{Type: "string"}, "Rated at or before, RFC3339 or YYYY-MM-DD (the whole day)"),
+	}
+	listErrors := errorResponses(http.StatusBadRequest, http.StatusServiceUnavailable)
+
+	stockList := func(id, path, summary string, params ...Parameter) route {
+		return route{http.MethodGet, path, Operation{
+			OperationID: id,
+			Summary:     summary,
+			Tags:        []string{"stocks"},
+			Parameters:  append(params, listParams...),
+			Responses:   ok(stocks, listErrors),
+		}}
+	}
+	adminOnly := func(op Operation) Operation {
+		op.Responses = merge(op.Responses, errorResponses(http.StatusUnauthorized, http.StatusForbidden))
+		op.Security = []map[string][]string{{"adminToken": {}}}
+		return op
+	}
+	admin := func(method, path, id, summary string, params []Parameter, responses map[string]Response) route {
+		return route{method, path, adminOnly(Operation{
+			OperationID: id,
+			Summary:     summary,
+			Tags:        []string{"admin"},
+			Parameters:  params,
+			Responses:   responses,
+		})}
+	}
+	syncJob := s.of(models.SyncJob{})
+	syncParams := []Parameter{
+		query("restart", &Schema{Type: "boolean"}, "Crawl from the first page instead of the checkpoint"),
+		query("dry_run", &Schema{Type: "boolean"}, "Crawl without writing and answer 200 with the report"),
+	}
+	syncResponses := merge(map[string]Response{
+		"200": jsonResponse("Dry run report", message(map[string]*Schema{"report": s.of(api.DiffReport{})})),
+		"202": {
+			Description: "Queued, or attached to the running job",
+			Headers:     map[string]Header{"Location": {Description: "The job", Schema: &Schema{Type: "string"}}},
+			Content:     jsonContent(message(map[string]*Schema{"job": syncJob})),
+		},
+	}, errorResponses(http.StatusServiceUnavailable))
+	scheduler := s.of(services.SchedulerStatus{})
+	quarantineFilter := []Parameter{
+		{Name: "id", In: "query", Description: "Quarantined row, repeatable", Schema: &Schema{Type: "array", Items: &Schema{Type: "integer"}}},
+		query("job_id", &Schema{Type: "integer"}, "Sync job the rows came from"),
+		query("before", dateTime(), "Quarantined before"),
+	}
+
+	return []route{
+		{http.MethodGet, "/api/sync", Operation{
+			OperationID: "runSync",
+			Summary:     "Queue a sync",
+			Description: "Same as POST /api/sync, it no longer waits for the sync to finish.",
+			Tags:        []string{"sync"},
+			Parameters:  syncParams,
+			Responses:   syncResponses,
+			Deprecated:  true,
+		}},
+		{http.MethodPost, "/api/sync", Operation{
+			OperationID: "enqueueSync",
+			Summary:     "Queue a sync",
+			Description: "Answers right away, the job is followed at its Location.",
+			Tags:        []string{"sync"},
+			Parameters:  syncParams,
+			Responses:   syncResponses,
+		}},
+		{http.MethodGet, "/api/sync/events", Operation{
+			OperationID: "streamSyncEvents",
+			Summary:     "Progress of the syncs as Server-Sent Events",
+			Tags:        []string{"sync"},
+			Responses: map[string]Response{"200": {
+				Description: "Event stream",
+				Content:     map[string]MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}},
+			}},
+		}},
+		{http.MethodGet, "/api/sync/jobs", Operation{
+			OperationID: "listSyncJobs",
+			Summary:     "Latest sync jobs",
+			Tags:        []string{"sync"},
+			Parameters:  []Parameter{query("limit", integer(20), "At most 100")},
+			Responses:   ok(object(map[string]*Schema{"items": {Type: "array", Items: syncJob}}), errorResponses(http.StatusServiceUnavailable)),
+		}},
+		{http.MethodGet, "/api/sync/jobs/{id}", Operation{
+			OperationID: "getSyncJob",
+			Summary:     "A sync job",
+			Tags:        []string{"sync"},
+			Parameters:  []Parameter{path("id", &Schema{Type: "integer"})},
+			Responses:   ok(syncJob, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable)),
+		}},
+		{http.MethodGet, "/api/sync/schedule", Operation{
+			OperationID: "getSyncSchedule",
+			Summary:     "State of the sync scheduler",
+			Tags:        []string{"sync"},
+			Responses:   ok(scheduler, nil),
+		}},
+		{http.MethodPost, "/api/sync/schedule/pause", adminOnly(Operation{
+			OperationID: "pauseSyncSchedule",
+			Summary:     "Pause the sync scheduler",
+			Tags:        []string{"sync"},
+			Responses:   ok(scheduler, errorResponses(http.StatusConflict)),
+		})},
+		{http.MethodPost, "/api/sync/schedule/resume", adminOnly(Operation{
+			OperationID: "resumeSyncSchedule",
+			Summary:     "Resume the sync scheduler",
+			Tags:        []string{"sync"},
+			Responses:   ok(scheduler, errorResponses(http.StatusConflict)),
+		})},
+
+		stockList("getStocks", "/api/stocks", "Stocks matching any combination of filters", filterParams...),
+		stockList("getAllStocks", "/api/stocks/all", "Every stock"),
+		{http.MethodGet, "/api/stocks/export", Operation{
+			OperationID: "exportStocks",
+			Summary:     "Download the stocks matching the filters",
+			Description: "Streamed newest first. The format comes from ?format= or else from the Accept header, csv by default. " +
+				"The NDJSON lines are the stocks of /api/v2, the CSV columns the same fields and can be imported back.",
+			Tags: []string{"export"},
+			Parameters: append([]Parameter{
+				query("format", &Schema{Type: "string", Enum: []string{services.ExportCSV, services.ExportNDJSON, services.ExportXLSX}}, "Takes precedence over Accept"),
+			}, filterParams...),
+			Responses: merge(map[string]Response{"200": {
+				Description: "The file, as an attachment",
+				Headers:     map[string]Header{"Content-Disposition": {Description: "attachment with the file name", Schema: &Schema{Type: "string"}}},
+				Content: map[string]MediaType{
+					"text/csv":             {Schema: &Schema{Type: "string"}},
+					"application/x-ndjson": {Schema: s.of(dto.Stock{})},
+					services.ExportContentTypes[services.ExportXLSX]: {Schema: &Schema{Type: "string", Format: "binary"}},
+				},
+			}}, errorResponses(http.StatusBadRequest, http.StatusNotAcceptable, http.StatusServiceUnavailable)),
+		}},
+		{http.MethodGet, "/api/stocks/sorted/{field}", Operation{
+			OperationID: "getSortedStocks",
+			Summary:     "Stocks sorted by a field",
+			Tags:        []string{"stocks"},
+			Parameters: append(append([]Parameter{
+				path("field", &Schema{Type: "string", Enum: repositories.SortFields()}),
+				query("order", &Schema{Type: "string", Enum: []string{"asc", "desc"}}, "Descending by default, except for ticker, company and brokerage"),
+			}, filterParams...), listParams...),
+			Responses: ok(stocks, listErrors),
+		}},
+		{http.MethodGet, "/api/stocks/search/{query}", Operation{
+			OperationID: "searchStocks",
+			Summary:     "Stocks ranked against a search",
+			Description: "Exact tickers first, then ticker prefixes, then company matches, tolerating typos. Paged by offset only.",
+			Tags:        []string{"stocks"},
+			Parameters:  []Parameter{path("query", &Schema{Type: "string"}), listParams[0], listParams[1], listParams[2]},
+			Responses:   ok(stocks, listErrors),
+		}},
+		stockList("getStocksByTicker", "/api/stocks/ticker/{ticker}", "Stocks of a ticker", path("ticker", &Schema{Type: "string"})),
+		{http.MethodGet, "/api/stocks/ticker/{ticker}/revisions", Operation{
+			OperationID: "getStockRevisions",
+			Summary:     "Upstream corrections of the ratings of a ticker",
+			Tags:        []string{"stocks"},
+			Parameters:  []Parameter{path("ticker", &Schema{Type: "string"}), listParams[0], listParams[1]},
+			Responses:   ok(b.page("StockRevisionPage", s.of(models.StockRevision{})), listErrors),
+		}},
+		stockList("getStocksByCompany", "/api/stocks/company/{company}", "Stocks of a company", path("company", &Schema{Type: "string"})),
+		stockList("getStocksByBrokerage", "/api/stocks/brokerage/{brokerage}", "Stocks rated by a brokerage", path("brokerage", &Schema{Type: "string"})),
+		stockList("getStocksByAction", "/api/stocks/action/{action}", "Stocks by the action of the rating", path("action", &Schema{Type: "string"})),
+		stockList("getStocksByRatingTo", "/api/stocks/rating-to/{rating}", "Stocks by their new rating", path("rating", &Schema{Type: "string"})),
+		stockList("getStocksByRatingFrom", "/api/stocks/rating-from/{rating}", "Stocks by their previous rating", path("rating", &Schema{Type: "string"})),
+		{http.MethodGet, "/api/stocks/price-range/{min}/{max}", Operation{
+			OperationID: "getStocksByPrice",
+			Summary:     "Stocks with a target_to in a range",
+			Tags:        []string{"stocks"},
+			Parameters:  append([]Parameter{path("min", &Schema{Type: "number"}), path("max", &Schema{Type: "number"})}, listParams...),
+			Responses:   ok(stocks, errorResponses(http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusServiceUnavailable)),
+		}},
+		{http.MethodGet, "/api/recommendations", Operation{
+			OperationID: "getRecommendations",
+			Summary:     "The 5 best scored stocks",
+			Tags:        []string{"recommendations"},
+			Responses:   ok(&Schema{Type: "array", Items: s.of(models.Recommendation{})}, errorResponses(http.StatusServiceUnavailable)),
+		}},
+
+		{http.MethodPost, "/api/import", adminOnly(Operation{
+			OperationID: "importRatings",
+			Summary:     "Import a CSV or NDJSON dump",
+			Description: "Rows that fail validation are skipped and reported by line.",
+			Tags:        []string{"import"},
+			Parameters: []Parameter{
+				query("format", &Schema{Type: "string", Enum: []string{api.FormatCSV, api.FormatNDJSON}}, "Guessed from the file extension by default"),
+				query("source", &Schema{Type: "string", Default: "import"}, "Source recorded on the rows"),
+			},
+			RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+				"multipart/form-data": {Schema: object(map[string]*Schema{"file": {Type: "string", Format: "binary"}})},
+			}},
+			Responses: ok(s.of(services.ImportReport{}), errorResponses(http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusServiceUnavailable)),
+		})},
+		{http.MethodGet, "/api/quarantine", Operation{
+			OperationID: "getQuarantine",
+			Summary:     "Upstream items that failed to parse",
+			Tags:        []string{"quarantine"},
+			Parameters:  append(quarantineFilter, listParams[0], listParams[1]),
+			Responses:   ok(b.page("QuarantinedStockPage", s.of(models.QuarantinedStock{})), listErrors),
+		}},
+		{http.MethodPost, "/api/quarantine/reprocess", adminOnly(Operation{
+			OperationID: "reprocessQuarantine",
+			Summary:     "Parse the quarantined items again and store the ones that pass",
+			Tags:        []string{"quarantine"},
+			Parameters:  quarantineFilter,
+			Responses:   ok(s.of(services.ReprocessResult{}), listErrors),
+		})},
+		{http.MethodDelete, "/api/quarantine", adminOnly(Operation{
+			OperationID: "purgeQuarantine",
+			Summary:     "Delete quarantined items",
+			Description: "Every quarantined item when no filter is given.",
+			Tags:        []string{"quarantine"},
+			Parameters:  quarantineFilter,
+			Responses:   ok(message(map[string]*Schema{"deleted": {Type: "integer"}}), listErrors),
+		})},
+
+		admin(http.MethodPost, "/api/admin/archive", "archiveStocks", "Move the stocks rated before a date to the archive",
+			[]Parameter{{Name: "before", In: "query", Required: true, Schema: dateTime()}},
+			ok(message(map[string]*Schema{"archived": {Type: "integer"}}), listErrors)),
+		admin(http.MethodPost, "/api/admin/archive/restore", "restoreArchive", "Move archived stocks back",
+			[]Parameter{query("since", dateTime(), "Only the ones rated at or after, every archived stock by default")},
+			ok(message(map[string]*Schema{"restored": {Type: "integer"}}), listErrors)),
+		admin(http.MethodGet, "/api/admin/snapshots", "listSnapshots", "Snapshots taken of the stocks table", nil,
+			ok(&Schema{Type: "array", Items: s.of(services.Snapshot{})}, errorResponses(http.StatusInternalServerError))),
+		admin(http.MethodPost, "/api/admin/snapshots", "createSnapshot", "Take a snapshot of the stocks table", nil,
+			merge(map[string]Response{"201": jsonResponse("Snapshot taken", s.of(services.Snapshot{}))}, errorResponses(http.StatusServiceUnavailable))),
+		admin(http.MethodPost, "/api/admin/snapshots/{name}/restore", "restoreSnapshot", "Insert back the rows of a snapshot",
+			[]Parameter{path("name", &Schema{Type: "string"})},
+			ok(message(map[string]*Schema{"result": s.of(services.RestoreResult{})}), errorResponses(http.StatusNotFound, http.StatusServiceUnavailable))),
+		admin(http.MethodDelete, "/api/admin/stocks", "purgeStocks", "Delete every stock, after a snapshot",
+			[]Parameter{query("confirm", &Schema{Type: "string"}, "Token of the 428 answer, without it nothing is deleted")},
+			merge(map[string]Response{
+				"200": jsonResponse("Deleted", message(map[string]*Schema{"result": s.of(services.PurgeResult{})})),
+				"428": jsonResponse("Repeat the request with the token to confirm", message(map[string]*Schema{"confirmation": s.of(services.PurgeConfirmation{})})),
+			}, errorResponses(http.StatusConflict, http.StatusServiceUnavailable))),
+
+		{http.MethodGet, "/api/openapi.json", Operation{
+			OperationID: "getOpenAPI",
+			Summary:     "This document",
+			Tags:        []string{"docs"},
+			Responses:   ok(&Schema{Type: "object"}, nil),
+		}},
+		{http.MethodGet, "/api/docs", Operation{
+			OperationID: "getDocs",
+			Summary:     "Browsable documentation",
+			Tags:        []string{"docs"},
+			Responses: map[string]Response{"200": {
+				Description: "HTML page",
+				Content:     map[string]MediaType{"text/html": {Schema: &Schema{Type: "string"}}},
+			}},
+		}},
+	}
+}
+
+// v2 derives the /api/v2 operations from the stock queries of /api, they
+// take the same parameters and answer with the bodies of package dto.
+func (b *builder) v2(v1 []route) []route {
+	stocks := b.schemas.of(dto.StockPage{})
+	recommendations := b.schemas.of(dto.RecommendationList{})
+
+	var routes []route
+	for _, r := range v1 {
+		var body *Schema
+		switch {
+		case r.Tags[0] == "recommendations":
+			body = recommendations
+		case r.Tags[0] == "stocks" && !strings.HasSuffix(r.Path, "/revisions"):
+			body = stocks
+		default:
+			continue
+		}
+
+		op := r.Operation
+		op.OperationID += "V2"
+		op.Tags = []string{"v2"}
+		op.Parameters = nil
+		for _, param := range r.Parameters {
+			if !param.Deprecated {
+				op.Parameters = append(op.Parameters, param)
+			}
+		}
+		op.Responses = map[string]Response{}
+		for status, response := range r.Responses {
+			op.Responses[status] = response
+		}
+		op.Responses["200"] = jsonResponse("OK", body)
+
+		routes = append(routes, route{r.Method, "/api/v2" + strings.TrimPrefix(r.Path, "/api"), op})
+	}
+	return routes
+}
+
+// defaultPageSize mirrors the one of the handlers.
+const defaultPageSize = 20
+
+// page registers name as a page of items and returns its reference.
+func (b *builder) page(name string, items *Schema) *Schema {
+	b.schemas.components[name] = object(map[string]*Schema{
+		"items":      {Type: "array", Items: items},
+		"pagination": ref("Pagination"),
+	})
+	return ref(name)
+}
+
+func object(props map[string]*Schema) *Schema {
+	required := make([]string, 0, len(props))
+	for name := range props {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return &Schema{Type: "object", Properties: props, Required: required}
+}
+
+func integer(def int) *Schema {
+	return &Schema{Type: "integer", Default: def}
+}
+
+func dateTime() *Schema {
+	return &Schema{Type: "string", Format: "date-time"}
+}
+
+func query(name string, schema *Schema, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func path(name string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "path", Required: true, Schema: schema}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+func jsonResponse(description string, schema *Schema) Response {
+	return Response{Description: description, Content: jsonContent(schema)}
+}
+
+func ok(schema *Schema, others map[string]Response) map[string]Response {
+	return merge(map[string]Response{"200": jsonResponse("OK", schema)}, others)
+}
+
+// errorResponses are the error envelopes of statuses, a 500 can always happen.
+func errorResponses(statuses ...int) map[string]Response {
+	responses := map[string]Response{
+		"500": jsonResponse(http.StatusText(http.StatusInternalServerError), ref("ErrorResponse")),
+	}
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = jsonResponse(http.StatusText(status), ref("ErrorResponse"))
+	}
+	return responses
+}
+
+func merge(responses, others map[string]Response) map[string]Response {
+	for status, response := range others {
+		if _, ok := responses[status]; !ok {
+			responses[status] = response
+		}
+	}
+	return responses
+}
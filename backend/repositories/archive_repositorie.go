@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const stockColumns = "ticker, target_from, target_to, company, action, brokerage, rating_from, rating_to, time, source"
+
+// archiveConflict replaces a row archived earlier under the same (ticker, time).
+const archiveConflict = "ON CONFLICT (ticker, time) DO UPDATE SET " +
+	"target_from = excluded.target_from, target_to = excluded.target_to, " +
+	"company = excluded.company, action = excluded.action, brokerage = excluded.brokerage, " +
+	"rating_from = excluded.rating_from, rating_to = excluded.rating_to, " +
+	"source = excluded.source, archived_at = excluded.archived_at"
+
+// ArchiveRepository moves stocks between the stocks and archived_stocks
+// tables and walks or empties the stocks table for the snapshots.
+type ArchiveRepository struct {
+	db *gorm.DB
+}
+
+func NewArchiveRepository(DB *gorm.DB) *ArchiveRepository {
+	return &ArchiveRepository{db: DB}
+}
+
+// ArchiveStocks moves the stocks rated before cutoff to archived_stocks. A
+// row archived earlier under the same (ticker, time) is replaced.
+func (r *ArchiveRepository) ArchiveStocks(ctx context.Context, before time.Time) (int, error) {
+	DB := r.db.WithContext(ctx)
+
+	// on Postgres a copy then a delete would, under READ COMMITTED, delete
+	// rows committed in between without archiving them: the rows are moved by
+	// a single statement instead
+	if DB.Dialector.Name() == "postgres" {
+		result := DB.Exec(
+			"WITH moved AS (DELETE FROM stocks WHERE time < ? RETURNING "+stockColumns+") "+
+				"INSERT INTO archived_stocks ("+stockColumns+", archived_at) "+
+				"SELECT "+stockColumns+", now() FROM moved "+archiveConflict,
+			before,
+		)
+		if result.Error != nil {
+			return 0, fmt.Errorf("can't move rows to the archive: %w", classify(result.Error))
+		}
+		return int(result.RowsAffected), nil
+	}
+
+	// SQLite has no DELETE in a WITH, its write transactions lock the whole
+	// database anyway
+	var archived int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"INSERT INTO archived_stocks ("+stockColumns+", archived_at) "+
+				"SELECT "+stockColumns+", ? FROM stocks WHERE time < ? "+archiveConflict,
+			time.Now().UTC(), before,
+		).Error; err != nil {
+			return fmt.Errorf("can't copy rows to the archive: %w", classify(err))
+		}
+
+		result := tx.Where("time < ?", before).Delete(&models.Stock{})
+		if result.Error != nil {
+			return fmt.Errorf("can't delete archived rows: %w", classify(result.Error))
+		}
+		archived = int(result.RowsAffected)
+		return nil
+	})
+	return archived, err
+}
+
+// RestoreArchived moves the archived stocks rated at or after since back to
+// the stocks table. Rows stored again in the meantime are kept as they are.
+func (r *ArchiveRepository) RestoreArchived(ctx context.Context, since time.Time) (int, error) {
+	DB := r.db.WithContext(ctx)
+
+	// moved by a single statement for the same reason as ArchiveStocks
+	if DB.Dialector.Name() == "postgres" {
+		result := DB.Exec(
+			"WITH moved AS (DELETE FROM archived_stocks WHERE time >= ? RETURNING "+stockColumns+") "+
+				"INSERT INTO stocks ("+stockColumns+") "+
+				"SELECT "+stockColumns+" FROM moved ON CONFLICT (ticker, time) DO NOTHING",
+			since,
+		)
+		if result.Error != nil {
+			return 0, fmt.Errorf("can't move rows from the archive: %w", classify(result.Error))
+		}
+		return int(result.RowsAffected), nil
+	}
+
+	var restored int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(
+			"INSERT INTO stocks ("+stockColumns+") "+
+				"SELECT "+stockColumns+" FROM archived_stocks WHERE time >= ? "+
+				"ON CONFLICT (ticker, time) DO NOTHING",
+			since,
+		)
+		if result.Error != nil {
+			return fmt.Errorf("can't copy rows from the archive: %w", classify(result.Error))
+		}
+		restored = int(result.RowsAffected)
+
+		if err := tx.Where("time >= ?", since).Delete(&models.ArchivedStock{}).Error; err != nil {
+			return fmt.Errorf("can't delete restored rows from the archive: %w", classify(err))
+		}
+		return nil
+	})
+	return restored, err
+}
+
+func (r *ArchiveRepository) CountStocks(ctx context.Context) (int, error) {
+	DB := r.db.WithContext(ctx)
+
+	var total int64
+	if err := DB.Model(&models.Stock{}).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("can't count stocks: %w", classify(err))
+	}
+	return int(total), nil
+}
+
+// EachStock calls fn with every stored stock, batch by batch in (ticker,
+// time) order.
+func (r *ArchiveRepository) EachStock(ctx context.Context, batchSize int, fn func([]models.Stock) error) error {
+	DB := r.db.WithContext(ctx)
+
+	var last *models.Stock
+	for {
+		query := DB.Order("ticker, time").Limit(batchSize)
+		if last != nil {
+			query = query.Where("ticker > ? OR (ticker = ? AND time > ?)", last.Ticker, last.Ticker, last.Time)
+		}
+
+		var stocks []models.Stock
+		if err := query.Find(&stocks).Error; err != nil {
+			return fmt.Errorf("can't read stocks: %w", classify(err))
+		}
+		if len(stocks) == 0 {
+			return nil
+		}
+		if err := fn(stocks); err != nil {
+			return err
+		}
+		last = &stocks[len(stocks)-1]
+	}
+}
+
+// PurgeStocks deletes every stored stock.
+func (r *ArchiveRepository) PurgeStocks(ctx context.Context) (int, error) {
+	DB := r.db.WithContext(ctx)
+
+	result := DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Stock{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("can't delete stocks: %w", classify(result.Error))
+	}
+	return int(result.RowsAffected), nil
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestArchiveMovesTheRowsBothWays(t *testing.T) {
+	ctx := context.Background()
+	repos, err := NewSQLiteRepositories(":memory:")
+	if err != nil {
+		t.Fatalf("can't open the repositories: %v", err)
+	}
+	if _, err := repos.Stocks.StoreStock(ctx, testStocks); err != nil {
+		t.Fatalf("can't store the stocks: %v", err)
+	}
+
+	// only the second AAPL rating is after the cutoff
+	cutoff := day.Add(time.Minute)
+	archived, err := repos.Archive.ArchiveStocks(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if archived != 3 {
+		t.Errorf("got %d archived, want 3", archived)
+	}
+	if got, _ := repos.Archive.CountStocks(ctx); got != 1 {
+		t.Errorf("got %d stocks left, want 1", got)
+	}
+	var inArchive int64
+	repos.Archive.db.Model(&models.ArchivedStock{}).Count(&inArchive)
+	if inArchive != 3 {
+		t.Errorf("got %d archived rows, want 3", inArchive)
+	}
+
+	restored, err := repos.Archive.RestoreArchived(ctx, day)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored != 3 {
+		t.Errorf("got %d restored, want 3", restored)
+	}
+	if got, _ := repos.Archive.CountStocks(ctx); got != 4 {
+		t.Errorf("got %d stocks, want 4", got)
+	}
+	repos.Archive.db.Model(&models.ArchivedStock{}).Count(&inArchive)
+	if inArchive != 0 {
+		t.Errorf("got %d rows left in the archive, want 0", inArchive)
+	}
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+)
+
+// BatchStore stores a batch of stocks at once: either the whole batch lands
+// or none of it does. Rows already stored are handled by policy, revisions
+// are attributed to jobID.
+type BatchStore interface {
+	StoreBatch(ctx context.Context, stocks []models.Stock, policy ConflictPolicy, jobID uint) (StoreResult, error)
+}
+
+// BatchWriter stores stocks in batches of a fixed size into a BatchStore, so
+// a failing batch never leaves half of its rows behind.
+type BatchWriter struct {
+	store  BatchStore
+	size   int
+	policy ConflictPolicy
+	jobID  uint
+}
+
+func NewBatchWriter(store BatchStore, size int, policy ConflictPolicy, jobID uint) *BatchWriter {
+	if size <= 0 {
+		size = 100
+	}
+	if policy == "" {
+		policy = ConflictIgnore
+	}
+	return &BatchWriter{store: store, size: size, policy: policy, jobID: jobID}
+}
+
+func (w *BatchWriter) Size() int {
+	return w.size
+}
+
+// Write stores stocks batch by batch and stops at the first batch that fails.
+// The result counts the rows of that batch as failed, the rows after it are
+// not attempted and not counted.
+func (w *BatchWriter) Write(ctx context.Context, stocks []models.Stock) (StoreResult, error) {
+	var total StoreResult
+
+	for start := 0; start < len(stocks); start += w.size {
+		batch := stocks[start:min(start+w.size, len(stocks))]
+
+		stored, err := w.store.StoreBatch(ctx, batch, w.policy, w.jobID)
+		if err != nil {
+			total.Failed += len(batch)
+			return total, err
+		}
+		total.Inserted += stored.Inserted
+		total.Updated += stored.Updated
+		total.Ignored += stored.Ignored
+	}
+	return total, nil
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Keyset pagination walks stocks newest first on the (time, ticker) key, so
+// every page costs the same however deep it is, unlike OFFSET.
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// approximateTotalCap bounds the count of a cursor page, past it the total
+// is reported as approximate.
+const approximateTotalCap = 10000
+
+// cursor is the key of the stock a page starts after, or ends before when
+// Before is set. It travels base64 encoded, clients treat it as opaque.
+type cursor struct {
+	Time   time.Time `json:"t"`
+	Ticker string    `json:"k"`
+	Before bool      `json:"b,omitempty"`
+}
+
+func encodeCursor(stock models.Stock, before bool) string {
+	data, _ := json.Marshal(cursor{Time: stock.Time, Ticker: stock.Ticker, Before: before})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Ticker == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// CursorPage is a page of stocks with the cursors of its neighbours, empty
+// when there is none. Total is only filled when asked for.
+type CursorPage struct {
+	Items       []models.Stock
+	NextCursor  string
+	PrevCursor  string
+	Total       int
+	Approximate bool // Total reached approximateTotalCap
+}
+
+// FindCursor returns up to limit stocks matching filter, newest first,
+// starting at the position encoded by cursorValue ("" for the first page).
+// filter.Sort doesn't apply, the order is the key's.
+func (r *GormStockRepository) FindCursor(ctx context.Context, filter StockFilter, cursorValue string, limit int, withTotal bool) (CursorPage, error) {
+	c, err := decodeCursor(cursorValue)
+	if err != nil {
+		return CursorPage{}, err
+	}
+	DB := r.db.WithContext(ctx)
+
+	query := filter.apply(DB.Model(&models.Stock{}))
+	switch {
+	case c == nil:
+		query = query.Order("time DESC, ticker DESC")
+	case c.Before:
+		query = query.Where("time > ? OR (time = ? AND ticker > ?)", c.Time, c.Time, c.Ticker).
+			Order("time, ticker")
+	default:
+		query = query.Where("time < ? OR (time = ? AND ticker < ?)", c.Time, c.Time, c.Ticker).
+			Order("time DESC, ticker DESC")
+	}
+
+	var stocks []models.Stock
+	if err := query.Limit(limit + 1).Find(&stocks).Error; err != nil {
+		return CursorPage{}, fmt.Errorf("can't find %w", classify(err))
+	}
+	page := cursorPage(stocks, c, limit)
+
+	if withTotal {
+		var total int64
+		capped := filter.apply(DB.Model(&models.Stock{})).Select("1").Limit(approximateTotalCap + 1)
+		if err := DB.Table("(?) AS matches", capped).Count(&total).Error; err != nil {
+			return CursorPage{}, fmt.Errorf("can't count %w", classify(err))
+		}
+		page.Total = min(int(total), approximateTotalCap)
+		page.Approximate = total > approximateTotalCap
+	}
+	return page, nil
+}
+
+func (r *MemoryStockRepository) FindCursor(_ context.Context, filter StockFilter, cursorValue string, limit int, withTotal bool) (CursorPage, error) {
+	c, err := decodeCursor(cursorValue)
+	if err != nil {
+		return CursorPage{}, err
+	}
+
+	matches := r.filter(filter.Matches)
+	// newest first, the order of the GORM query without a cursor
+	slices.SortFunc(matches, func(a, b models.Stock) int {
+		return -compareKey(a, b)
+	})
+
+	var stocks []models.Stock
+	switch {
+	case c == nil:
+		stocks = matches
+	case c.Before:
+		for i := len(matches) - 1; i >= 0; i-- {
+			if compareKey(matches[i], models.Stock{Time: c.Time, Ticker: c.Ticker}) > 0 {
+				stocks = append(stocks, matches[i])
+			}
+		}
+	default:
+		for _, stock := range matches {
+			if compareKey(stock, models.Stock{Time: c.Time, Ticker: c.Ticker}) < 0 {
+				stocks = append(stocks, stock)
+			}
+		}
+	}
+	page := cursorPage(stocks[:min(len(stocks), limit+1)], c, limit)
+
+	if withTotal {
+		page.Total = min(len(matches), approximateTotalCap)
+		page.Approximate = len(matches) > approximateTotalCap
+	}
+	return page, nil
+}
+
+// compareKey orders stocks on the (time, ticker) key.
+func compareKey(a, b models.Stock) int {
+	if c := a.Time.Compare(b.Time); c != 0 {
+		return c
+	}
+	switch {
+	case a.Ticker < b.Ticker:
+		return -1
+	case a.Ticker > b.Ticker:
+		return 1
+	}
+	return 0
+}
+
+// cursorPage trims the up to limit+1 stocks read from c into a page, newest
+// first, with the cursors around it.
+func cursorPage(stocks []models.Stock, c *cursor, limit int) CursorPage {
+	more := len(stocks) > limit
+	stocks = stocks[:min(len(stocks), limit)]
+
+	page := CursorPage{Items: stocks}
+	if c != nil && c.Before {
+		// read oldest first going backwards
+		slices.Reverse(stocks)
+		if more && len(stocks) > 0 {
+			page.PrevCursor = encodeCursor(stocks[0], true)
+		}
+		if len(stocks) > 0 {
+			page.NextCursor = encodeCursor(stocks[len(stocks)-1], false)
+		}
+		return page
+	}
+
+	if more {
+		page.NextCursor = encodeCursor(stocks[len(stocks)-1], false)
+	}
+	if c != nil && len(stocks) > 0 {
+		page.PrevCursor = encodeCursor(stocks[0], true)
+	}
+	return page
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrUnavailable = errors.New("database unavailable")
+	ErrTimeout     = errors.New("database timeout")
+)
+
+// dbError is an error of the database tagged with the sentinel of its kind,
+// its message is left untouched.
+type dbError struct {
+	err  error
+	kind error
+}
+
+func (e *dbError) Error() string        { return e.err.Error() }
+func (e *dbError) Unwrap() error        { return e.err }
+func (e *dbError) Is(target error) bool { return target == e.kind }
+
+// classify tags err with ErrTimeout when the query ran out of time and with
+// ErrUnavailable when the database couldn't be reached, so the handlers can
+// tell them apart from a failing query. Any other error is returned as is.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var state interface{ SQLState() string }
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &dbError{err: err, kind: ErrTimeout}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &dbError{err: err, kind: ErrTimeout}
+	case errors.As(err, &state):
+		switch code := state.SQLState(); {
+		case code == "57014": // query_canceled, statement_timeout included
+			return &dbError{err: err, kind: ErrTimeout}
+		case strings.HasPrefix(code, "08"), code == "57P01", code == "57P02", code == "57P03":
+			// connection exceptions and the server shutting down or starting
+			return &dbError{err: err, kind: ErrUnavailable}
+		}
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		return &dbError{err: err, kind: ErrUnavailable}
+	case strings.Contains(err.Error(), "database is locked"):
+		// SQLite gave up waiting on busy_timeout
+		return &dbError{err: err, kind: ErrUnavailable}
+	}
+	return err
+}
+
+// unavailable tags err, the failure to get a connection, with ErrUnavailable.
+func unavailable(err error) error {
+	return &dbError{err: err, kind: ErrUnavailable}
+}
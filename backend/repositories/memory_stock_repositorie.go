@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// MemoryStockRepository keeps stocks in a map, for tests and for running
+// handlers and services without a database.
+type MemoryStockRepository struct {
+	mu     sync.RWMutex
+	stocks map[string]models.Stock
+}
+
+func NewMemoryStockRepository(stocks ...models.Stock) *MemoryStockRepository {
+	r := &MemoryStockRepository{stocks: map[string]models.Stock{}}
+	r.StoreStock(context.Background(), stocks)
+	return r
+}
+
+func (r *MemoryStockRepository) StoreStock(_ context.Context, stocks []models.Stock) (StoreResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result StoreResult
+	for _, stock := range stocks {
+		if _, ok := r.stocks[stock.Key()]; ok {
+			result.Ignored++
+			continue
+		}
+		r.stocks[stock.Key()] = stock
+		result.Inserted++
+	}
+	return result, nil
+}
+
+// StoreBatch applies policy like the GORM repository, except that no
+// revisions are kept: ConflictVersion updates like ConflictOverwrite.
+func (r *MemoryStockRepository) StoreBatch(_ context.Context, stocks []models.Stock, policy ConflictPolicy, _ uint) (StoreResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result StoreResult
+	seen := make(map[string]bool, len(stocks))
+	for _, stock := range stocks {
+		key := stock.Key()
+		current, stored := r.stocks[key]
+		switch {
+		case seen[key]:
+			result.Ignored++
+		case !stored:
+			r.stocks[key] = stock
+			result.Inserted++
+		case policy == ConflictIgnore || len(current.Diff(stock)) == 0:
+			result.Ignored++
+		default:
+			r.stocks[key] = stock
+			result.Updated++
+		}
+		seen[key] = true
+	}
+	return result, nil
+}
+
+func (r *MemoryStockRepository) Find(_ context.Context, filter StockFilter, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	stocks := r.filter(filter.Matches)
+	slices.SortStableFunc(stocks, func(a, b models.Stock) int {
+		return compareStocks(a, b, filter.Sort)
+	})
+	offset := (page - 1) * pageSize
+	start := min(offset, len(stocks))
+	end := min(start+pageSize, len(stocks))
+	return stocks[start:end], page, offset, len(stocks), nil
+}
+
+func (r *MemoryStockRepository) GetAll(ctx context.Context, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByTicker(ctx context.Context, ticker string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Ticker: ticker}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByCompany(ctx context.Context, company string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Company: company}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByBrokerage(ctx context.Context, brokerage string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Brokerage: brokerage}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByAction(ctx context.Context, action string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Action: action}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByRatingTo(ctx context.Context, ratingTo string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{RatingTo: ratingTo}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByRatingFrom(ctx context.Context, ratingFrom string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{RatingFrom: ratingFrom}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByPrice(ctx context.Context, min, max float64, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{TargetMin: &min, TargetMax: &max}, page, pageSize)
+}
+
+func (r *MemoryStockRepository) GetByRecommendation(_ context.Context) ([]models.Stock, error) {
+	stocks := r.filter(func(models.Stock) bool { return true })
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return stocks[i].Time.After(stocks[j].Time)
+	})
+	return stocks, nil
+}
+
+func (r *MemoryStockRepository) GetExisting(_ context.Context, stocks []models.Stock) ([]models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var existing []models.Stock
+	for _, stock := range stocks {
+		if stored, ok := r.stocks[stock.Key()]; ok {
+			existing = append(existing, stored)
+		}
+	}
+	return existing, nil
+}
+
+// filter returns the matching stocks in (ticker, time) order, like the
+// GORM repository does.
+func (r *MemoryStockRepository) filter(match func(models.Stock) bool) []models.Stock {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stocks := []models.Stock{}
+	for _, stock := range r.stocks {
+		if match(stock) {
+			stocks = append(stocks, stock)
+		}
+	}
+	sort.Slice(stocks, func(i, j int) bool {
+		if stocks[i].Ticker != stocks[j].Ticker {
+			return stocks[i].Ticker < stocks[j].Ticker
+		}
+		return stocks[i].Time.Before(stocks[j].Time)
+	})
+	return stocks
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type QuarantineFilter struct {
+	IDs    []uint
+	JobID  uint
+	Before time.Time
+}
+
+// QuarantineRepository stores the upstream items ConvertStockApi rejected.
+type QuarantineRepository struct {
+	db *gorm.DB
+}
+
+func NewQuarantineRepository(DB *gorm.DB) *QuarantineRepository {
+	return &QuarantineRepository{db: DB}
+}
+
+func (r *QuarantineRepository) StoreQuarantined(ctx context.Context, rows []models.QuarantinedStock) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	DB := r.db.WithContext(ctx)
+
+	if err := DB.Create(&rows).Error; err != nil {
+		return fmt.Errorf("can't insert quarantined rows: %w", classify(err))
+	}
+	return nil
+}
+
+func (r *QuarantineRepository) GetQuarantined(ctx context.Context, filter QuarantineFilter, page, pageSize int) ([]models.QuarantinedStock, int, error) {
+	DB := r.db.WithContext(ctx)
+
+	offset := (page - 1) * pageSize
+
+	var rows []models.QuarantinedStock
+	if err := filter.apply(DB.Model(&models.QuarantinedStock{})).
+		Order("id").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&rows).
+		Error; err != nil {
+		return nil, 0, fmt.Errorf("can't find %w", classify(err))
+	}
+
+	var totalItems int64
+	filter.apply(DB.Model(&models.QuarantinedStock{})).Count(&totalItems)
+
+	return rows, int(totalItems), nil
+}
+
+// GetQuarantinedAfter returns up to limit rows matching filter with an id
+// greater than afterID, for walking the whole table in chunks.
+func (r *QuarantineRepository) GetQuarantinedAfter(ctx context.Context, filter QuarantineFilter, afterID uint, limit int) ([]models.QuarantinedStock, error) {
+	DB := r.db.WithContext(ctx)
+
+	var rows []models.QuarantinedStock
+	if err := filter.apply(DB.Model(&models.QuarantinedStock{})).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&rows).
+		Error; err != nil {
+		return nil, fmt.Errorf("can't find %w", classify(err))
+	}
+	return rows, nil
+}
+
+func (r *QuarantineRepository) UpdateQuarantinedError(ctx context.Context, id uint, message string) error {
+	DB := r.db.WithContext(ctx)
+
+	if err := DB.Model(&models.QuarantinedStock{}).
+		Where("id = ?", id).
+		Update("error", message).
+		Error; err != nil {
+		return fmt.Errorf("can't update quarantined row: %w", classify(err))
+	}
+	return nil
+}
+
+func (r *QuarantineRepository) DeleteQuarantined(ctx context.Context, filter QuarantineFilter) (int, error) {
+	DB := r.db.WithContext(ctx)
+
+	// without a condition GORM refuses to delete, purging everything is explicit
+	query := filter.apply(DB.Model(&models.QuarantinedStock{}))
+	if len(filter.IDs) == 0 && filter.JobID == 0 && filter.Before.IsZero() {
+		query = query.Where("1 = 1")
+	}
+
+	result := query.Delete(&models.QuarantinedStock{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("can't delete quarantined rows: %w", classify(result.Error))
+	}
+	return int(result.RowsAffected), nil
+}
+
+func (f QuarantineFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.IDs) > 0 {
+		query = query.Where("id IN ?", f.IDs)
+	}
+	if f.JobID != 0 {
+		query = query.Where("job_id = ?", f.JobID)
+	}
+	if !f.Before.IsZero() {
+		query = query.Where("created_at < ?", f.Before)
+	}
+	return query
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"backend/db"
+	"backend/migrations"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Repositories are the repositories sharing one database handle, the pool
+// opened at startup or a SQLite database.
+type Repositories struct {
+	Stocks     StockRepository
+	Sync       *SyncRepository
+	Quarantine *QuarantineRepository
+	Revisions  *RevisionRepository
+	Archive    *ArchiveRepository
+}
+
+func NewRepositories(DB *gorm.DB) *Repositories {
+	return &Repositories{
+		Stocks:     NewGormStockRepository(DB),
+		Sync:       NewSyncRepository(DB),
+		Quarantine: NewQuarantineRepository(DB),
+		Revisions:  NewRevisionRepository(DB),
+		Archive:    NewArchiveRepository(DB),
+	}
+}
+
+// NewSQLiteRepositories opens (or creates) the SQLite database at path,
+// ":memory:" works too, migrates it and returns its repositories.
+func NewSQLiteRepositories(path string) (*Repositories, error) {
+	DB, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRepositories(DB), nil
+}
+
+func openSQLite(path string) (*gorm.DB, error) {
+	DB, err := db.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open sqlite database: %w", classify(err))
+	}
+	// every connection to ":memory:" would see its own empty database
+	if sqlDB, err := DB.DB(); err == nil && strings.Contains(path, ":memory:") {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if _, err := migrations.Up(DB, 0); err != nil {
+		return nil, fmt.Errorf("can't migrate sqlite database: %w", classify(err))
+	}
+	return DB, nil
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ConflictPolicy decides what happens when a rating arrives for a (ticker,
+// time) that is already stored with different values.
+type ConflictPolicy string
+
+const (
+	ConflictIgnore    ConflictPolicy = "ignore"    // keep the stored values
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace them
+	ConflictVersion   ConflictPolicy = "version"   // replace them and record a StockRevision
+)
+
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(value)); policy {
+	case "":
+		return ConflictIgnore, nil
+	case ConflictIgnore, ConflictOverwrite, ConflictVersion:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q, expected ignore, overwrite or version", value)
+	}
+}
+
+// storeWithPolicy stores stocks in DB, which should be a transaction for the
+// update and the revision to land together.
+func storeWithPolicy(DB *gorm.DB, stocks []models.Stock, policy ConflictPolicy, jobID uint) (StoreResult, error) {
+	if policy == ConflictIgnore {
+		return storeStock(DB, stocks)
+	}
+
+	existing, err := getExisting(DB, stocks)
+	if err != nil {
+		return StoreResult{}, err
+	}
+	stored := make(map[string]models.Stock, len(existing))
+	for _, stock := range existing {
+		stored[stock.Key()] = stock
+	}
+
+	var result StoreResult
+	var fresh []models.Stock
+	var revisions []models.StockRevision
+	seen := make(map[string]bool, len(stocks))
+
+	for _, stock := range stocks {
+		key := stock.Key()
+		if seen[key] {
+			result.Ignored++
+			continue
+		}
+		seen[key] = true
+
+		current, ok := stored[key]
+		if !ok {
+			fresh = append(fresh, stock)
+			continue
+		}
+		fields := current.Diff(stock)
+		if len(fields) == 0 {
+			result.Ignored++
+			continue
+		}
+
+		if err := DB.Model(&models.Stock{}).
+			Where("ticker = ? AND time = ?", current.Ticker, current.Time).
+			Updates(map[string]interface{}{
+				"target_from": stock.TargetFrom,
+				"target_to":   stock.TargetTo,
+				"company":     stock.Company,
+				"action":      stock.Action,
+				"brokerage":   stock.Brokerage,
+				"rating_from": stock.RatingFrom,
+				"rating_to":   stock.RatingTo,
+				"source":      stock.Source,
+			}).Error; err != nil {
+			return StoreResult{}, fmt.Errorf("can't update data: %w", classify(err))
+		}
+		result.Updated++
+
+		if policy == ConflictVersion {
+			revisions = append(revisions, models.StockRevision{
+				Ticker:   current.Ticker,
+				Time:     current.Time,
+				Fields:   strings.Join(fields, ","),
+				Previous: current.Values(),
+				New:      stock.Values(),
+				Source:   stock.Source,
+				JobID:    jobID,
+			})
+		}
+	}
+
+	inserted, err := storeStock(DB, fresh)
+	if err != nil {
+		return StoreResult{}, err
+	}
+	result.Inserted += inserted.Inserted
+	result.Ignored += inserted.Ignored
+
+	if len(revisions) > 0 {
+		if err := DB.Create(&revisions).Error; err != nil {
+			return StoreResult{}, fmt.Errorf("can't insert revisions: %w", classify(err))
+		}
+	}
+	return result, nil
+}
+
+// RevisionRepository reads the revisions recorded by the version policy.
+type RevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepository(DB *gorm.DB) *RevisionRepository {
+	return &RevisionRepository{db: DB}
+}
+
+func (r *RevisionRepository) GetRevisions(ctx context.Context, ticker string, page, pageSize int) ([]models.StockRevision, int, error) {
+	DB := r.db.WithContext(ctx)
+
+	offset := (page - 1) * pageSize
+
+	var revisions []models.StockRevision
+	if err := DB.
+		Where("ticker = ?", ticker).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&revisions).
+		Error; err != nil {
+		return nil, 0, fmt.Errorf("can't find %w", classify(err))
+	}
+
+	var totalItems int64
+	DB.Model(&models.StockRevision{}).
+		Where("ticker = ?", ticker).
+		Count(&totalItems)
+
+	return revisions, int(totalItems), nil
+}
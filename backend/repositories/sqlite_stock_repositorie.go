@@ -0,0 +1,11 @@
+package repositories
+
+// NewSQLiteStockRepository opens (or creates) the SQLite database at path,
+// ":memory:" works too, and returns a GORM repository on top of it.
+func NewSQLiteStockRepository(path string) (*GormStockRepository, error) {
+	DB, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGormStockRepository(DB), nil
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"backend/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MatchMode is how StockFilter compares its text fields, always ignoring case.
+type MatchMode string
+
+const (
+	MatchContains MatchMode = "contains"
+	MatchExact    MatchMode = "exact"
+	MatchPrefix   MatchMode = "prefix"
+)
+
+func ParseMatchMode(value string) (MatchMode, error) {
+	switch mode := MatchMode(strings.ToLower(value)); mode {
+	case "":
+		return MatchContains, nil
+	case MatchContains, MatchExact, MatchPrefix:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown match mode %q, expected contains, exact or prefix", value)
+	}
+}
+
+// StockFilter combines criteria on stocks, the zero value of a field leaves
+// it out. Text fields are compared with Match, Query matches the ticker or
+// the company, TargetMin and TargetMax bound target_to and From and To bound
+// the rating time, all bounds inclusive. Sort orders the results, by
+// (ticker, time) after its own fields.
+type StockFilter struct {
+	Query      string
+	Ticker     string
+	Company    string
+	Brokerage  string
+	Action     string
+	RatingFrom string
+	RatingTo   string
+	Match      MatchMode
+
+	TargetMin *float64
+	TargetMax *float64
+	From      time.Time
+	To        time.Time
+
+	Sort []StockOrder
+}
+
+func (f StockFilter) textFields() []struct{ column, value string } {
+	return []struct{ column, value string }{
+		{"ticker", f.Ticker},
+		{"company", f.Company},
+		{"brokerage", f.Brokerage},
+		{"action", f.Action},
+		{"rating_from", f.RatingFrom},
+		{"rating_to", f.RatingTo},
+	}
+}
+
+func (f StockFilter) apply(DB *gorm.DB) *gorm.DB {
+	if f.Query != "" {
+		pattern := f.pattern(f.Query)
+		DB = DB.Where(DB.Session(&gorm.Session{NewDB: true}).
+			Where(ilike(DB, "ticker"), pattern).
+			Or(ilike(DB, "company"), pattern))
+	}
+	for _, field := range f.textFields() {
+		if field.value != "" {
+			DB = DB.Where(ilike(DB, field.column), f.pattern(field.value))
+		}
+	}
+	if f.TargetMin != nil {
+		DB = DB.Where("target_to >= ?", *f.TargetMin)
+	}
+	if f.TargetMax != nil {
+		DB = DB.Where("target_to <= ?", *f.TargetMax)
+	}
+	if !f.From.IsZero() {
+		DB = DB.Where("time >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		DB = DB.Where("time <= ?", f.To)
+	}
+	return DB
+}
+
+// pattern turns value into a LIKE pattern for the match mode, wildcards in
+// value match themselves.
+func (f StockFilter) pattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	switch f.Match {
+	case MatchExact:
+		return value
+	case MatchPrefix:
+		return value + "%"
+	default:
+		return "%" + value + "%"
+	}
+}
+
+// Matches tells whether stock passes the filter, the in-memory counterpart
+// of apply.
+func (f StockFilter) Matches(stock models.Stock) bool {
+	if f.Query != "" && !f.matchText(stock.Ticker, f.Query) && !f.matchText(stock.Company, f.Query) {
+		return false
+	}
+	values := []string{stock.Ticker, stock.Company, stock.Brokerage, stock.Action, stock.RatingFrom, stock.RatingTo}
+	for i, field := range f.textFields() {
+		if field.value != "" && !f.matchText(values[i], field.value) {
+			return false
+		}
+	}
+	if f.TargetMin != nil && stock.TargetTo < *f.TargetMin {
+		return false
+	}
+	if f.TargetMax != nil && stock.TargetTo > *f.TargetMax {
+		return false
+	}
+	if !f.From.IsZero() && stock.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && stock.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+func (f StockFilter) matchText(value, want string) bool {
+	value, want = strings.ToLower(value), strings.ToLower(want)
+	switch f.Match {
+	case MatchExact:
+		return value == want
+	case MatchPrefix:
+		return strings.HasPrefix(value, want)
+	default:
+		return strings.Contains(value, want)
+	}
+}
@@ -1,258 +1,189 @@
 package repositories
 
 import (
-	"backend/db"
 	"backend/models"
+	"context"
 	"fmt"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-func StoreStock(stocks []models.Stock) (string, error) {
-	if len(stocks) == 0 {
-		return "", nil
-	}
-	DB, err := db.Conect()
-	if err != nil {
-		return "", fmt.Errorf("can't conect to database: %v", err)
-	}
-
-	result := DB.Clauses(clause.OnConflict{
-		Columns: []clause.Column{
-			{Name: "ticker"},
-			{Name: "time"},
-		},
-		DoNothing: true,
-	}).Create(&stocks)
-
-	if result.Error != nil {
-		return "", fmt.Errorf("can't insert data: %v", result.Error)
-	}
+// GormStockRepository is the StockRepository backed by GORM, on Postgres
+// (CockroachDB) or SQLite.
+type GormStockRepository struct {
+	db *gorm.DB
+}
 
-	message := fmt.Sprintf("Inserted: %d, Ignored: %d\n", result.RowsAffected, len(stocks)-int(result.RowsAffected))
-	return message, nil
+// NewGormStockRepository wraps a handle opened once at startup, every call
+// borrows a connection from its pool.
+func NewGormStockRepository(DB *gorm.DB) *GormStockRepository {
+	return &GormStockRepository{db: DB}
 }
 
-func GetAll(page, pageSize int) ([]models.Stock, int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
+// ilike is a case-insensitive match on column with backslash escapes, SQLite
+// has no ILIKE but its LIKE already ignores case.
+func ilike(DB *gorm.DB, column string) string {
+	if DB.Dialector.Name() == "postgres" {
+		return column + ` ILIKE ? ESCAPE '\'`
 	}
+	return column + ` LIKE ? ESCAPE '\'`
+}
 
-	offset := (page - 1) * pageSize
+type StoreResult struct {
+	Inserted int
+	Updated  int // already stored with other values, replaced by the conflict policy
+	Ignored  int // already stored under the same (ticker, time)
+	Failed   int // rows of batches that couldn't be written
+}
 
-	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Find(&stocks).
-		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get all data:  %v", err)
-	}
+func (r StoreResult) String() string {
+	return fmt.Sprintf("Inserted: %d, Ignored: %d\n", r.Inserted, r.Ignored)
+}
 
-	var totalItems int64
-	DB.Model(&models.Stock{}).Count(&totalItems)
+func (r *GormStockRepository) StoreStock(ctx context.Context, stocks []models.Stock) (StoreResult, error) {
+	DB := r.db.WithContext(ctx)
 
-	return stocks, page, offset, int(totalItems),  nil
+	return storeStock(DB, stocks)
 }
 
-func GetByTicker(ticker string, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
+// StoreBatch stores stocks in one transaction, so an update and its revision
+// always land together.
+func (r *GormStockRepository) StoreBatch(ctx context.Context, stocks []models.Stock, policy ConflictPolicy, jobID uint) (StoreResult, error) {
+	DB := r.db.WithContext(ctx)
+
+	var stored StoreResult
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		stored, err = storeWithPolicy(tx, stocks, policy, jobID)
+		return err
+	})
 	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
-	}
-
-	offset := (page - 1) * pageSize
-
-	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Where("ticker ILIKE ?", "%"+ticker+"%").
-		Find(&stocks).
-		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
+		return StoreResult{}, err
 	}
-
-	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("ticker ILIKE ?", "%"+ticker+"%").
-		Count(&totalItems)
-
-
-	return stocks, page, offset, int(totalItems), nil
+	return stored, nil
 }
 
-func GetByCompany(company string, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
+func storeStock(DB *gorm.DB, stocks []models.Stock) (StoreResult, error) {
+	// GORM refuses to insert an empty slice, which happens whenever every
+	// row of a batch is already stored
+	if len(stocks) == 0 {
+		return StoreResult{}, nil
 	}
 
-	offset := (page - 1) * pageSize
+	result := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "ticker"},
+			{Name: "time"},
+		},
+		DoNothing: true,
+	}).Create(&stocks)
 
-	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Where("company ILIKE ?", "%"+company+"%").
-		Find(&stocks).
-		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
+	if result.Error != nil {
+		return StoreResult{}, fmt.Errorf("can't insert data: %w", classify(result.Error))
 	}
 
-	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("company ILIKE ?", "%"+company+"%").
-		Count(&totalItems)
-
-	return stocks, page, offset, int(totalItems), nil
+	return StoreResult{
+		Inserted: int(result.RowsAffected),
+		Ignored:  len(stocks) - int(result.RowsAffected),
+	}, nil
 }
 
-func GetByBrokerage(brokerage string, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
-	}
+// Find returns the page of stocks matching filter, in filter.Sort order.
+func (r *GormStockRepository) Find(ctx context.Context, filter StockFilter, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	DB := r.db.WithContext(ctx)
 
 	offset := (page - 1) * pageSize
 
 	var stocks []models.Stock
-	if err := DB.
+	if err := applySort(filter.apply(DB.Model(&models.Stock{})), filter.Sort).
 		Offset(offset).
 		Limit(pageSize).
-		Where("brokerage ILIKE ?", "%"+brokerage+"%").
 		Find(&stocks).
 		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
+		return nil, 1, 20, 0, fmt.Errorf("can't find %w", classify(err))
 	}
 
 	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("brokerage ILIKE ?", "%"+brokerage+"%").
-		Count(&totalItems)
+	if err := filter.apply(DB.Model(&models.Stock{})).
+		Count(&totalItems).
+		Error; err != nil {
+		return nil, 1, 20, 0, fmt.Errorf("can't count %w", classify(err))
+	}
 
 	return stocks, page, offset, int(totalItems), nil
 }
 
-func GetByAction(action string, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
-	}
-
-	offset := (page - 1) * pageSize
-
-	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Where("action ILIKE ?", "%"+action+"%").
-		Find(&stocks).
-		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
-	}
-
-	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("action ILIKE ?", "%"+action+"%").
-		Count(&totalItems)
+func (r *GormStockRepository) GetAll(ctx context.Context, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{}, page, pageSize)
+}
 
-	return stocks, page, offset, int(totalItems), nil
+func (r *GormStockRepository) GetByTicker(ctx context.Context, ticker string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Ticker: ticker}, page, pageSize)
 }
 
-func GetByRatingTo(ratingTo string, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
-	}
+func (r *GormStockRepository) GetByCompany(ctx context.Context, company string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Company: company}, page, pageSize)
+}
 
-	offset := (page - 1) * pageSize
+func (r *GormStockRepository) GetByBrokerage(ctx context.Context, brokerage string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Brokerage: brokerage}, page, pageSize)
+}
 
-	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Where("rating_to ILIKE ?", "%"+ratingTo+"%").
-		Find(&stocks).
-		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
-	}
+func (r *GormStockRepository) GetByAction(ctx context.Context, action string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{Action: action}, page, pageSize)
+}
 
-	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("rating_to ILIKE ?", "%"+ratingTo+"%").
-		Count(&totalItems)
+func (r *GormStockRepository) GetByRatingTo(ctx context.Context, ratingTo string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{RatingTo: ratingTo}, page, pageSize)
+}
 
-	return stocks, page, offset, int(totalItems), nil
+func (r *GormStockRepository) GetByRatingFrom(ctx context.Context, ratingFrom string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{RatingFrom: ratingFrom}, page, pageSize)
 }
 
-func GetByRatingFrom(ratingFrom string, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
-	}
+func (r *GormStockRepository) GetByPrice(ctx context.Context, min, max float64, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	return r.Find(ctx, StockFilter{TargetMin: &min, TargetMax: &max}, page, pageSize)
+}
 
-	offset := (page - 1) * pageSize
+func (r *GormStockRepository) GetByRecommendation(ctx context.Context) ([]models.Stock, error) {
+	DB := r.db.WithContext(ctx)
 
 	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Where("rating_from ILIKE ?", "%"+ratingFrom+"%").
+	if err := DB.Model(&models.Stock{}).
+		Order("time DESC").
 		Find(&stocks).
 		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
+		return nil, fmt.Errorf("can't find %w", classify(err))
 	}
 
-	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("rating_from ILIKE ?", "%"+ratingFrom+"%").
-		Count(&totalItems)
-
-	return stocks, page, offset, int(totalItems), nil
+	return stocks, nil
 }
 
-func GetByPrice(min, max float64, page, pageSize int) ([]models.Stock,int, int, int, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't get conection: %v", err)
-	}
+// GetExisting returns the stored stocks sharing a (ticker, time) key with any
+// of the given ones.
+func (r *GormStockRepository) GetExisting(ctx context.Context, stocks []models.Stock) ([]models.Stock, error) {
+	DB := r.db.WithContext(ctx)
 
-	offset := (page - 1) * pageSize
+	return getExisting(DB, stocks)
+}
 
-	var stocks []models.Stock
-	if err := DB.
-		Offset(offset).
-		Limit(pageSize).
-		Where("target_to BETWEEN ? AND ?", min, max).
-		Find(&stocks).
-		Error; err != nil {
-		return nil, 1, 20, 0, fmt.Errorf("can't find %v", err)
+func getExisting(DB *gorm.DB, stocks []models.Stock) ([]models.Stock, error) {
+	if len(stocks) == 0 {
+		return nil, nil
 	}
 
-	var totalItems int64
-	DB.Model(&models.Stock{}).
-		Where("target_to BETWEEN ? AND ?", min, max).
-		Count(&totalItems)
-
-	return stocks, page, offset, int(totalItems), nil
-}
-
-func GetByRecommendation() ([]models.Stock, error) {
-	DB, err := db.Conect()
-	if err != nil {
-		return nil, fmt.Errorf("can't get conection: %v", err)
+	keys := make([][]interface{}, 0, len(stocks))
+	for _, stock := range stocks {
+		keys = append(keys, []interface{}{stock.Ticker, stock.Time})
 	}
 
-	var stocks []models.Stock
-	if err := DB.Model(&models.Stock{}).
-		Order("time DESC").
-		Find(&stocks).
+	var existing []models.Stock
+	if err := DB.
+		Where("(ticker, time) IN ?", keys).
+		Find(&existing).
 		Error; err != nil {
-		return nil, fmt.Errorf("can't find %v", err)
+		return nil, fmt.Errorf("can't find %w", classify(err))
 	}
-
-	return stocks, nil
+	return existing, nil
 }
-
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+)
+
+// StockRepository is the stock storage the handlers and services depend on.
+// Queries are bound to ctx. The paginated getters return the page, the
+// offset of the page, the total of matching items and an error.
+type StockRepository interface {
+	StoreStock(ctx context.Context, stocks []models.Stock) (StoreResult, error)
+	Find(ctx context.Context, filter StockFilter, page, pageSize int) ([]models.Stock, int, int, int, error)
+	FindCursor(ctx context.Context, filter StockFilter, cursor string, limit int, withTotal bool) (CursorPage, error)
+	Search(ctx context.Context, query string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetAll(ctx context.Context, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByTicker(ctx context.Context, ticker string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByCompany(ctx context.Context, company string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByBrokerage(ctx context.Context, brokerage string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByAction(ctx context.Context, action string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByRatingTo(ctx context.Context, ratingTo string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByRatingFrom(ctx context.Context, ratingFrom string, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByPrice(ctx context.Context, min, max float64, page, pageSize int) ([]models.Stock, int, int, int, error)
+	GetByRecommendation(ctx context.Context) ([]models.Stock, error)
+	GetExisting(ctx context.Context, stocks []models.Stock) ([]models.Stock, error)
+	BatchStore
+}
+
+var (
+	_ StockRepository = (*GormStockRepository)(nil)
+	_ StockRepository = (*MemoryStockRepository)(nil)
+)
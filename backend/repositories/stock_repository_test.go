@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var testStocks = []models.Stock{
+	{Ticker: "AAPL", TargetFrom: 100, TargetTo: 150, Company: "Apple Inc.", Action: "upgraded by", Brokerage: "UBS", RatingFrom: "Hold", RatingTo: "Buy", Time: day},
+	{Ticker: "AAPL", TargetFrom: 150, TargetTo: 120, Company: "Apple Inc.", Action: "downgraded by", Brokerage: "Barclays", RatingFrom: "Buy", RatingTo: "Sell", Time: day.Add(time.Hour)},
+	{Ticker: "MSFT", TargetFrom: 300, TargetTo: 330, Company: "Microsoft Corporation", Action: "target raised by", Brokerage: "UBS", RatingFrom: "Buy", RatingTo: "Buy", Time: day},
+	{Ticker: "KO", TargetFrom: 60, TargetTo: 60, Company: "The Coca-Cola Company", Action: "reiterated by", Brokerage: "Citigroup", RatingFrom: "Neutral", RatingTo: "Neutral", Time: day},
+}
+
+// implementations runs test against every StockRepository, each seeded with
+// testStocks, so they are held to the same behavior.
+func implementations(t *testing.T, test func(t *testing.T, repo StockRepository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStockRepository(testStocks...))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repo, err := NewSQLiteStockRepository(":memory:")
+		if err != nil {
+			t.Fatalf("can't open the repository: %v", err)
+		}
+		if _, err := repo.StoreStock(context.Background(), testStocks); err != nil {
+			t.Fatalf("can't store the stocks: %v", err)
+		}
+		test(t, repo)
+	})
+}
+
+func tickers(stocks []models.Stock) []string {
+	var tickers []string
+	for _, stock := range stocks {
+		tickers = append(tickers, stock.Ticker)
+	}
+	return tickers
+}
+
+func TestStoreStockIgnoresStoredRows(t *testing.T) {
+	implementations(t, func(t *testing.T, repo StockRepository) {
+		fresh := models.Stock{Ticker: "NVDA", TargetFrom: 100, TargetTo: 140, Time: day}
+		result, err := repo.StoreStock(context.Background(), []models.Stock{testStocks[0], fresh})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Inserted != 1 || result.Ignored != 1 {
+			t.Errorf("got %+v, want 1 inserted and 1 ignored", result)
+		}
+
+		existing, err := repo.GetExisting(context.Background(), []models.Stock{fresh, testStocks[2]})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := tickers(existing); !slices.Equal(got, []string{"NVDA", "MSFT"}) && !slices.Equal(got, []string{"MSFT", "NVDA"}) {
+			t.Errorf("got %v existing, want NVDA and MSFT", got)
+		}
+	})
+}
+
+func TestGetters(t *testing.T) {
+	ctx := context.Background()
+	implementations(t, func(t *testing.T, repo StockRepository) {
+		tests := []struct {
+			name  string
+			get   func() ([]models.Stock, int, int, int, error)
+			want  []string
+			total int
+		}{
+			{"all", func() ([]models.Stock, int, int, int, error) { return repo.GetAll(ctx, 1, 10) }, []string{"AAPL", "AAPL", "KO", "MSFT"}, 4},
+			{"second page", func() ([]models.Stock, int, int, int, error) { return repo.GetAll(ctx, 2, 3) }, []string{"MSFT"}, 4},
+			{"ticker", func() ([]models.Stock, int, int, int, error) { return repo.GetByTicker(ctx, "AAPL", 1, 10) }, []string{"AAPL", "AAPL"}, 2},
+			{"brokerage", func() ([]models.Stock, int, int, int, error) { return repo.GetByBrokerage(ctx, "UBS", 1, 10) }, []string{"AAPL", "MSFT"}, 2},
+			{"rating to", func() ([]models.Stock, int, int, int, error) { return repo.GetByRatingTo(ctx, "Neutral", 1, 10) }, []string{"KO"}, 1},
+			{"price", func() ([]models.Stock, int, int, int, error) { return repo.GetByPrice(ctx, 100, 200, 1, 10) }, []string{"AAPL", "AAPL"}, 2},
+		}
+
+		for _, test := range tests {
+			items, _, _, total, err := test.get()
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.name, err)
+			}
+			if got := tickers(items); !slices.Equal(got, test.want) || total != test.total {
+				t.Errorf("%s: got %v of %d, want %v of %d", test.name, got, total, test.want, test.total)
+			}
+		}
+	})
+}
+
+func TestFindSorts(t *testing.T) {
+	implementations(t, func(t *testing.T, repo StockRepository) {
+		tests := []struct {
+			sort string
+			want []string
+		}{
+			{"-upside", []string{"AAPL", "MSFT", "KO", "AAPL"}},
+			{"rating_to", []string{"AAPL", "MSFT", "KO", "AAPL"}},
+			{"-target_to", []string{"MSFT", "AAPL", "AAPL", "KO"}},
+			{"action", []string{"AAPL", "KO", "MSFT", "AAPL"}},
+		}
+
+		for _, test := range tests {
+			sort, err := ParseStockSort(test.sort)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.sort, err)
+			}
+			items, _, _, _, err := repo.Find(context.Background(), StockFilter{Sort: sort}, 1, 10)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.sort, err)
+			}
+			if got := tickers(items); !slices.Equal(got, test.want) {
+				t.Errorf("%s: got %v, want %v", test.sort, got, test.want)
+			}
+		}
+	})
+}
+
+func TestParseStockSortRejectsUnknownFields(t *testing.T) {
+	if _, err := ParseStockSort("ticker,price"); err == nil {
+		t.Errorf("got no error sorting by price")
+	}
+}
+
+func TestStoreBatchAppliesThePolicy(t *testing.T) {
+	changed := testStocks[0]
+	changed.TargetTo = 175
+	fresh := models.Stock{Ticker: "NVDA", TargetFrom: 100, TargetTo: 140, Time: day}
+
+	tests := []struct {
+		policy ConflictPolicy
+		want   StoreResult
+		stored float64 // TargetTo of testStocks[0] afterwards
+	}{
+		{ConflictIgnore, StoreResult{Inserted: 1, Ignored: 3}, 150},
+		{ConflictOverwrite, StoreResult{Inserted: 1, Updated: 1, Ignored: 2}, 175},
+		{ConflictVersion, StoreResult{Inserted: 1, Updated: 1, Ignored: 2}, 175},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			implementations(t, func(t *testing.T, repo StockRepository) {
+				// testStocks[2] is unchanged and fresh is sent twice
+				batch := []models.Stock{changed, testStocks[2], fresh, fresh}
+				result, err := NewBatchWriter(repo, 10, test.policy, 0).Write(context.Background(), batch)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if result != test.want {
+					t.Errorf("got %+v, want %+v", result, test.want)
+				}
+
+				items, _, _, _, err := repo.GetByTicker(context.Background(), "AAPL", 1, 10)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := items[0].TargetTo; got != test.stored {
+					t.Errorf("got target_to %v, want %v", got, test.stored)
+				}
+			})
+		})
+	}
+}
+
+// TestFindCursorPagesBothWays walks testStocks newest first two at a time,
+// then back from the second page.
+func TestFindCursorPagesBothWays(t *testing.T) {
+	ctx := context.Background()
+	implementations(t, func(t *testing.T, repo StockRepository) {
+		first, err := repo.FindCursor(ctx, StockFilter{}, "", 2, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := tickers(first.Items); !slices.Equal(got, []string{"AAPL", "MSFT"}) || first.Total != 4 {
+			t.Errorf("first page: got %v of %d, want [AAPL MSFT] of 4", got, first.Total)
+		}
+		if first.NextCursor == "" || first.PrevCursor != "" {
+			t.Fatalf("first page: got next %q and prev %q, want only a next cursor", first.NextCursor, first.PrevCursor)
+		}
+
+		second, err := repo.FindCursor(ctx, StockFilter{}, first.NextCursor, 2, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := tickers(second.Items); !slices.Equal(got, []string{"KO", "AAPL"}) {
+			t.Errorf("second page: got %v, want [KO AAPL]", got)
+		}
+		if second.NextCursor != "" || second.PrevCursor == "" {
+			t.Fatalf("second page: got next %q and prev %q, want only a prev cursor", second.NextCursor, second.PrevCursor)
+		}
+
+		back, err := repo.FindCursor(ctx, StockFilter{}, second.PrevCursor, 2, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := tickers(back.Items); !slices.Equal(got, []string{"AAPL", "MSFT"}) || !back.Items[0].Time.Equal(day.Add(time.Hour)) {
+			t.Errorf("back: got %v, want the first page again", back.Items)
+		}
+		if back.NextCursor != first.NextCursor || back.PrevCursor != "" {
+			t.Errorf("back: got next %q and prev %q, want the cursors of the first page", back.NextCursor, back.PrevCursor)
+		}
+
+		if _, err := repo.FindCursor(ctx, StockFilter{}, "not a cursor", 2, false); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("got %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestSearchRanksTheTiers(t *testing.T) {
+	ctx := context.Background()
+	// one match of "ms" in each tier, besides MSFT
+	ranked := []models.Stock{
+		{Ticker: "GS", TargetFrom: 400, TargetTo: 450, Company: "Goldman Sachs", Brokerage: "Williams Trading", RatingTo: "Buy", Time: day},
+		{Ticker: "ADS", TargetFrom: 90, TargetTo: 95, Company: "Adams Resources", Brokerage: "Citigroup", RatingTo: "Hold", Time: day},
+		{Ticker: "MS", TargetFrom: 80, TargetTo: 85, Company: "Morgan Stanley", Brokerage: "Citigroup", RatingTo: "Buy", Time: day},
+	}
+
+	implementations(t, func(t *testing.T, repo StockRepository) {
+		if _, err := repo.StoreStock(ctx, ranked); err != nil {
+			t.Fatalf("can't store the stocks: %v", err)
+		}
+
+		tests := []struct {
+			query    string
+			page     int
+			pageSize int
+			want     []string
+			total    int
+		}{
+			// exact ticker, ticker prefix, company, brokerage
+			{"ms", 1, 10, []string{"MS", "MSFT", "ADS", "GS"}, 4},
+			{"MS", 2, 2, []string{"ADS", "GS"}, 4},
+			{"coca", 1, 10, []string{"KO"}, 1},
+			{"%", 1, 10, nil, 0},
+		}
+
+		for _, test := range tests {
+			items, _, _, total, err := repo.Search(ctx, test.query, test.page, test.pageSize)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", test.query, err)
+			}
+			if got := tickers(items); !slices.Equal(got, test.want) || total != test.total {
+				t.Errorf("%s page %d: got %v of %d, want %v of %d", test.query, test.page, got, total, test.want, test.total)
+			}
+		}
+	})
+}
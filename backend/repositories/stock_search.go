@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"backend/models"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Search ranks stocks against a free text query in tiers: exact ticker,
+// ticker prefix, company match, then any other match on brokerage or ratings
+// or a typo tolerant (trigram) match on ticker, company or brokerage. Within
+// a tier the closest trigram similarity comes first.
+//
+// Typo tolerance needs pg_trgm (see the stock_search_indexes migration), on
+// SQLite only the exact, prefix and contains tiers apply.
+const (
+	searchTierExact = 4 - iota
+	searchTierPrefix
+	searchTierCompany
+	searchTierOther
+)
+
+// trgmFields are matched by similarity on Postgres, they carry GIN trigram
+// indexes.
+var trgmFields = []string{"ticker", "company", "brokerage"}
+
+// searchQuery returns DB filtered to the matches of query and the ORDER BY
+// ranking them. The ranking ends with (ticker, time) itself, GORM drops an
+// ORDER BY expression as soon as other columns are added to it.
+func searchQuery(DB *gorm.DB, query string) (*gorm.DB, clause.Expr) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	contains := "%" + escaped + "%"
+
+	conditions := DB.Session(&gorm.Session{NewDB: true})
+	for _, column := range []string{"ticker", "company", "brokerage", "rating_to", "rating_from"} {
+		conditions = conditions.Or(ilike(DB, column), contains)
+	}
+
+	tier := clause.Expr{
+		SQL: fmt.Sprintf("CASE WHEN LOWER(ticker) = LOWER(?) THEN %d WHEN %s THEN %d WHEN %s THEN %d ELSE %d END DESC",
+			searchTierExact, ilike(DB, "ticker"), searchTierPrefix, ilike(DB, "company"), searchTierCompany, searchTierOther),
+		Vars:               []interface{}{query, escaped + "%", contains},
+		WithoutParentheses: true,
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		tier.SQL += ", ticker, time"
+		return DB.Where(conditions), tier
+	}
+
+	similarities := make([]string, 0, len(trgmFields))
+	vars := make([]interface{}, 0, len(trgmFields))
+	for _, column := range trgmFields {
+		conditions = conditions.Or(column+" % ?", query)
+		similarities = append(similarities, "similarity("+column+", ?)")
+		vars = append(vars, query)
+	}
+	tier.SQL += ", GREATEST(" + strings.Join(similarities, ", ") + ") DESC, ticker, time"
+	tier.Vars = append(tier.Vars, vars...)
+
+	return DB.Where(conditions), tier
+}
+
+// Search returns the page of stocks matching query, best matches first.
+func (r *GormStockRepository) Search(ctx context.Context, query string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	DB := r.db.WithContext(ctx)
+
+	offset := (page - 1) * pageSize
+
+	matches, rank := searchQuery(DB.Model(&models.Stock{}), query)
+
+	var stocks []models.Stock
+	if err := matches.
+		Order(clause.OrderBy{Expression: rank}).
+		Offset(offset).
+		Limit(pageSize).
+		Find(&stocks).
+		Error; err != nil {
+		return nil, 1, 20, 0, fmt.Errorf("can't search %w", classify(err))
+	}
+
+	var totalItems int64
+	matches, _ = searchQuery(DB.Model(&models.Stock{}), query)
+	if err := matches.Count(&totalItems).Error; err != nil {
+		return nil, 1, 20, 0, fmt.Errorf("can't count %w", classify(err))
+	}
+
+	return stocks, page, offset, int(totalItems), nil
+}
+
+// trgmThreshold is the default pg_trgm.similarity_threshold.
+const trgmThreshold = 0.3
+
+// searchRank is the in-memory counterpart of searchQuery, a tier of 0 means
+// no match.
+func searchRank(stock models.Stock, query string) (int, float64) {
+	q := strings.ToLower(query)
+	ticker, company := strings.ToLower(stock.Ticker), strings.ToLower(stock.Company)
+
+	score := max(trigramSimilarity(stock.Ticker, query), trigramSimilarity(stock.Company, query), trigramSimilarity(stock.Brokerage, query))
+
+	switch {
+	case ticker == q:
+		return searchTierExact, score
+	case strings.HasPrefix(ticker, q):
+		return searchTierPrefix, score
+	case strings.Contains(company, q):
+		return searchTierCompany, score
+	}
+	for _, value := range []string{stock.Ticker, stock.Brokerage, stock.RatingTo, stock.RatingFrom} {
+		if strings.Contains(strings.ToLower(value), q) {
+			return searchTierOther, score
+		}
+	}
+	if score >= trgmThreshold {
+		return searchTierOther, score
+	}
+	return 0, 0
+}
+
+// trigramSimilarity mirrors pg_trgm's similarity(): the shared trigrams of
+// a and b over all their distinct trigrams, words padded with two spaces in
+// front and one behind.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(value string) map[string]bool {
+	set := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+func (r *MemoryStockRepository) Search(_ context.Context, query string, page, pageSize int) ([]models.Stock, int, int, int, error) {
+	type ranked struct {
+		stock models.Stock
+		tier  int
+		score float64
+	}
+
+	var matches []ranked
+	for _, stock := range r.filter(func(models.Stock) bool { return true }) {
+		if tier, score := searchRank(stock, query); tier > 0 {
+			matches = append(matches, ranked{stock, tier, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b ranked) int {
+		if c := cmp.Compare(b.tier, a.tier); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.score, a.score)
+	})
+
+	offset := (page - 1) * pageSize
+	start := min(offset, len(matches))
+	end := min(start+pageSize, len(matches))
+	stocks := make([]models.Stock, 0, end-start)
+	for _, match := range matches[start:end] {
+		stocks = append(stocks, match.stock)
+	}
+	return stocks, page, offset, len(matches), nil
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"backend/models"
+	"cmp"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockOrder sorts stocks on one of the whitelisted SortFields.
+type StockOrder struct {
+	Field string
+	Desc  bool
+}
+
+// upsideExpr is the upside of a rating in percent, 0 when target_from is 0.
+const upsideExpr = "(CASE WHEN target_from = 0 THEN 0 ELSE (target_to - target_from) * 100.0 / target_from END)"
+
+// sortFields maps the sortable fields to their SQL expression.
+var sortFields = map[string]string{
+	"ticker":      "ticker",
+	"company":     "company",
+	"brokerage":   "brokerage",
+	"action":      "action",
+	"rating_from": "rating_from",
+	"rating_to":   "rating_to",
+	"time":        "time",
+	"target_to":   "target_to",
+	"target_from": "target_from",
+	"upside":      upsideExpr,
+}
+
+// SortFields lists the fields stocks can be sorted on.
+func SortFields() []string {
+	return []string{"ticker", "company", "brokerage", "action", "rating_from", "rating_to", "time", "target_to", "target_from", "upside"}
+}
+
+// ParseSortField accepts a sortable field as snake_case, camelCase or Go
+// field name ("target_to", "targetTo", "TargetTo").
+func ParseSortField(value string) (string, error) {
+	key := strings.ToLower(strings.ReplaceAll(value, "_", ""))
+	for _, field := range SortFields() {
+		if strings.ReplaceAll(field, "_", "") == key {
+			return field, nil
+		}
+	}
+	if key == "upsidepercentage" || key == "upsidepct" {
+		return "upside", nil
+	}
+	return "", fmt.Errorf("can't sort by %q, expected one of %s", value, strings.Join(SortFields(), ", "))
+}
+
+// ParseStockSort reads a comma separated list of fields, each optionally
+// prefixed with "-" for descending order: "-upside,ticker".
+func ParseStockSort(value string) ([]StockOrder, error) {
+	var orders []StockOrder
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := strings.HasPrefix(part, "-")
+		field, err := ParseSortField(strings.TrimLeft(part, "+-"))
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, StockOrder{Field: field, Desc: desc})
+	}
+	return orders, nil
+}
+
+// applySort orders DB by orders and then by (ticker, time), so pages stay
+// stable between requests.
+func applySort(DB *gorm.DB, orders []StockOrder) *gorm.DB {
+	for _, order := range orders {
+		expr, ok := sortFields[order.Field]
+		if !ok {
+			continue
+		}
+		DB = DB.Order(clause.OrderByColumn{Column: clause.Column{Name: expr, Raw: true}, Desc: order.Desc})
+	}
+	return DB.Order("ticker").Order("time")
+}
+
+func upside(stock models.Stock) float64 {
+	if stock.TargetFrom == 0 {
+		return 0
+	}
+	return (stock.TargetTo - stock.TargetFrom) * 100 / stock.TargetFrom
+}
+
+// compareStocks is the in-memory counterpart of applySort.
+func compareStocks(a, b models.Stock, orders []StockOrder) int {
+	for _, order := range orders {
+		var c int
+		switch order.Field {
+		case "ticker":
+			c = cmp.Compare(a.Ticker, b.Ticker)
+		case "company":
+			c = cmp.Compare(a.Company, b.Company)
+		case "brokerage":
+			c = cmp.Compare(a.Brokerage, b.Brokerage)
+		case "action":
+			c = cmp.Compare(a.Action, b.Action)
+		case "rating_from":
+			c = cmp.Compare(a.RatingFrom, b.RatingFrom)
+		case "rating_to":
+			c = cmp.Compare(a.RatingTo, b.RatingTo)
+		case "time":
+			c = a.Time.Compare(b.Time)
+		case "target_to":
+			c = cmp.Compare(a.TargetTo, b.TargetTo)
+		case "target_from":
+			c = cmp.Compare(a.TargetFrom, b.TargetFrom)
+		case "upside":
+			c = cmp.Compare(upside(a), upside(b))
+		}
+		if order.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	if c := cmp.Compare(a.Ticker, b.Ticker); c != 0 {
+		return c
+	}
+	return a.Time.Compare(b.Time)
+}
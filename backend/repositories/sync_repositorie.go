@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncRepository stores the sync jobs and the checkpoints of the sources.
+type SyncRepository struct {
+	db *gorm.DB
+}
+
+func NewSyncRepository(DB *gorm.DB) *SyncRepository {
+	return &SyncRepository{db: DB}
+}
+
+func (r *SyncRepository) GetCheckpoint(ctx context.Context, source string) (models.SyncCheckpoint, error) {
+	DB := r.db.WithContext(ctx)
+
+	var checkpoint models.SyncCheckpoint
+	if err := DB.
+		Where("source = ?", source).
+		Limit(1).
+		Find(&checkpoint).
+		Error; err != nil {
+		return models.SyncCheckpoint{}, fmt.Errorf("can't find checkpoint %w", classify(err))
+	}
+
+	checkpoint.Source = source
+	return checkpoint, nil
+}
+
+func (r *SyncRepository) SaveCheckpoint(ctx context.Context, checkpoint models.SyncCheckpoint) error {
+	DB := r.db.WithContext(ctx)
+
+	checkpoint.ID = 0
+	if err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}},
+		DoUpdates: clause.AssignmentColumns([]string{"next_page", "batches", "completed", "updated_at"}),
+	}).Create(&checkpoint).Error; err != nil {
+		return fmt.Errorf("can't save checkpoint: %w", classify(err))
+	}
+
+	return nil
+}
+
+func (r *SyncRepository) CreateSyncJob(ctx context.Context, job *models.SyncJob) error {
+	DB := r.db.WithContext(ctx)
+
+	if err := DB.Create(job).Error; err != nil {
+		return fmt.Errorf("can't create sync job: %w", classify(err))
+	}
+	return nil
+}
+
+func (r *SyncRepository) SaveSyncJob(ctx context.Context, job models.SyncJob) error {
+	DB := r.db.WithContext(ctx)
+
+	if err := DB.Save(&job).Error; err != nil {
+		return fmt.Errorf("can't save sync job: %w", classify(err))
+	}
+	return nil
+}
+
+func (r *SyncRepository) GetSyncJob(ctx context.Context, id uint) (models.SyncJob, error) {
+	DB := r.db.WithContext(ctx)
+
+	var job models.SyncJob
+	result := DB.Where("id = ?", id).Limit(1).Find(&job)
+	if result.Error != nil {
+		return models.SyncJob{}, fmt.Errorf("can't find %w", classify(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return models.SyncJob{}, fmt.Errorf("sync job %d: %w", id, ErrNotFound)
+	}
+	return job, nil
+}
+
+func (r *SyncRepository) ListSyncJobs(ctx context.Context, limit int) ([]models.SyncJob, error) {
+	DB := r.db.WithContext(ctx)
+
+	var jobs []models.SyncJob
+	if err := DB.
+		Order("id DESC").
+		Limit(limit).
+		Find(&jobs).
+		Error; err != nil {
+		return nil, fmt.Errorf("can't find %w", classify(err))
+	}
+	return jobs, nil
+}
+
+// FailUnfinishedSyncJobs marks jobs left queued or running by a previous
+// process as failed, they can't be running anymore.
+func (r *SyncRepository) FailUnfinishedSyncJobs(ctx context.Context) error {
+	DB := r.db.WithContext(ctx)
+
+	if err := DB.Model(&models.SyncJob{}).
+		Where("state IN ?", []string{models.SyncJobQueued, models.SyncJobRunning}).
+		Updates(map[string]interface{}{
+			"state": models.SyncJobFailed,
+			"error": "interrupted by server restart",
+		}).Error; err != nil {
+		return fmt.Errorf("can't update sync jobs: %w", classify(err))
+	}
+	return nil
+}
@@ -2,33 +2,96 @@ package routes
 
 import (
 	"backend/handlers"
+	"backend/openapi"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
+// Handlers are the handlers StockRoutes serves.
+type Handlers struct {
+	Stocks     *handlers.StockHandler
+	Revisions  *handlers.RevisionHandler
+	Sync       *handlers.SyncHandler
+	Import     *handlers.ImportHandler
+	Quarantine *handlers.QuarantineHandler
+	Admin      *handlers.AdminHandler
+}
 
-func StockRoutes() chi.Router {
+func StockRoutes(h Handlers) chi.Router {
 	r := chi.NewRouter()
+	stocks := h.Stocks
+
+	r.Use(middleware.RequestID)
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"https://*", "http://*"},
-		AllowedMethods: []string{"GET"},
+		AllowedMethods: []string{"GET", "POST", "DELETE"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-Request-Id"},
+		ExposedHeaders: []string{"X-Request-Id", "Retry-After"},
 		MaxAge: 300,
 	}))
 	
+	r.NotFound(handlers.NotFound)
+	r.MethodNotAllowed(handlers.MethodNotAllowed)
+
+	r.Get("/api/sync", h.Sync.EnqueueSync)
+	r.Post("/api/sync", h.Sync.EnqueueSync)
+	r.Get("/api/sync/events", h.Sync.StreamSyncEvents)
+	r.Get("/api/sync/jobs", h.Sync.ListSyncJobs)
+	r.Get("/api/sync/jobs/{id}", h.Sync.GetSyncJob)
+	r.Get("/api/sync/schedule", h.Sync.GetSyncSchedule)
+	r.With(handlers.RequireAdmin).Post("/api/sync/schedule/pause", h.Sync.PauseSyncSchedule)
+	r.With(handlers.RequireAdmin).Post("/api/sync/schedule/resume", h.Sync.ResumeSyncSchedule)
+	r.Get("/api/stocks", stocks.GetStocks)
+	r.Get("/api/stocks/all", stocks.GetAllStoreData)
+	r.Get("/api/stocks/export", stocks.ExportStocks)
+	r.Get("/api/stocks/sorted/{field}", stocks.GetSortedStocks)
+	r.Get("/api/stocks/search/{query}", stocks.SearchStocks)
+	r.Get("/api/stocks/ticker/{ticker}", stocks.GetStoreByTicker)
+	r.Get("/api/stocks/ticker/{ticker}/revisions", h.Revisions.GetStockRevisions)
+	r.Get("/api/stocks/company/{company}", stocks.GetStoreByCompany)
+	r.Get("/api/stocks/brokerage/{brokerage}", stocks.GetStoreByBrokerage)
+	r.Get("/api/stocks/action/{action}", stocks.GetStoreByAction)
+	r.Get("/api/stocks/rating-to/{rating}", stocks.GetStoreByRatingTo)
+	r.Get("/api/stocks/rating-from/{rating}", stocks.GetStoreByRatingFrom)
+	r.Get("/api/stocks/price-range/{min}/{max}", stocks.GetStoreByPrice)
+	r.Get("/api/recommendations", stocks.GetStoreByRecommendation)
+	r.With(handlers.RequireAdmin).Post("/api/import", h.Import.ImportRatings)
+	r.Get("/api/quarantine", h.Quarantine.GetQuarantine)
+	r.With(handlers.RequireAdmin).Post("/api/quarantine/reprocess", h.Quarantine.ReprocessQuarantine)
+	r.With(handlers.RequireAdmin).Delete("/api/quarantine", h.Quarantine.PurgeQuarantine)
+
+	// /api/v2 serves the same queries with typed snake_case bodies
+	v2 := stocks.V2()
+	r.Route("/api/v2", func(r chi.Router) {
+		r.Get("/stocks", v2.GetStocks)
+		r.Get("/stocks/all", v2.GetAllStoreData)
+		r.Get("/stocks/sorted/{field}", v2.GetSortedStocks)
+		r.Get("/stocks/search/{query}", v2.SearchStocks)
+		r.Get("/stocks/ticker/{ticker}", v2.GetStoreByTicker)
+		r.Get("/stocks/company/{company}", v2.GetStoreByCompany)
+		r.Get("/stocks/brokerage/{brokerage}", v2.GetStoreByBrokerage)
+		r.Get("/stocks/action/{action}", v2.GetStoreByAction)
+		r.Get("/stocks/rating-to/{rating}", v2.GetStoreByRatingTo)
+		r.Get("/stocks/rating-from/{rating}", v2.GetStoreByRatingFrom)
+		r.Get("/stocks/price-range/{min}/{max}", v2.GetStoreByPrice)
+		r.Get("/recommendations", v2.GetStoreByRecommendation)
+	})
+
+	r.Get("/api/openapi.json", openapi.Handler)
+	r.Get("/api/docs", openapi.DocsHandler)
 
-	r.Get("/api/sync", handlers.FetchAndStoreStock)
-	r.Get("/api/stocks/all", handlers.GetAllStoreData)
-	r.Get("/api/stocks/ticker/{ticker}", handlers.GetStoreByTicker)
-	r.Get("/api/stocks/company/{company}", handlers.GetStoreByCompany)
-	r.Get("/api/stocks/brokerage/{brokerage}", handlers.GetStoreByBrokerage)
-	r.Get("/api/stocks/action/{action}", handlers.GetStoreByAction)
-	r.Get("/api/stocks/rating-to/{rating}", handlers.GetStoreByRatingTo)
-	r.Get("/api/stocks/rating-from/{rating}", handlers.GetStoreByRatingFrom)
-	r.Get("/api/stocks/price-range/{min}/{max}", handlers.GetStoreByPrice)
-	r.Get("/api/recommendations", handlers.GetStoreByRecommendation)
-	r.Get("/api/sync/del",  handlers.DeleteTable)
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(handlers.RequireAdmin)
+		r.Post("/archive", h.Admin.ArchiveStocks)
+		r.Post("/archive/restore", h.Admin.RestoreArchive)
+		r.Get("/snapshots", h.Admin.ListSnapshots)
+		r.Post("/snapshots", h.Admin.CreateSnapshot)
+		r.Post("/snapshots/{name}/restore", h.Admin.RestoreSnapshot)
+		r.Delete("/stocks", h.Admin.PurgeStocks)
+	})
 
 	return r
 }
\ No newline at end of file
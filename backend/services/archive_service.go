@@ -0,0 +1,155 @@
+package services
+
+import (
+	"backend/config"
+	"backend/repositories"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const purgeConfirmationTTL = 5 * time.Minute
+
+var ErrInvalidConfirmation = errors.New("invalid or expired confirmation token")
+
+// ArchiveService archives, snapshots and purges the stocks.
+type ArchiveService struct {
+	archive *repositories.ArchiveRepository
+	stocks  repositories.StockRepository
+	cfg     config.ArchiveConfig
+
+	purgeMu     sync.Mutex
+	purgeTokens map[string]time.Time
+
+	retention sync.WaitGroup
+}
+
+func NewArchiveService(archive *repositories.ArchiveRepository, stocks repositories.StockRepository, cfg config.ArchiveConfig) *ArchiveService {
+	return &ArchiveService{
+		archive:     archive,
+		stocks:      stocks,
+		cfg:         cfg,
+		purgeTokens: map[string]time.Time{},
+	}
+}
+
+func (s *ArchiveService) Archive(ctx context.Context, before time.Time) (int, error) {
+	return s.archive.ArchiveStocks(ctx, before)
+}
+
+func (s *ArchiveService) Restore(ctx context.Context, since time.Time) (int, error) {
+	return s.archive.RestoreArchived(ctx, since)
+}
+
+// StartRetention archives, every RetentionInterval, the stocks rated more
+// than RetentionMaxAge ago, until ctx is done. It does nothing when
+// RetentionMaxAge is 0.
+func (s *ArchiveService) StartRetention(ctx context.Context) {
+	cfg := s.cfg
+	if cfg.RetentionMaxAge <= 0 {
+		log.Println("Retention disabled, set RETENTION_MAX_AGE to enable it")
+		return
+	}
+	if cfg.RetentionInterval <= 0 {
+		cfg.RetentionInterval = 24 * time.Hour
+	}
+	log.Println("Retention archiving stocks older than", cfg.RetentionMaxAge, "every", cfg.RetentionInterval)
+
+	s.retention.Add(1)
+	go func() {
+		defer s.retention.Done()
+		ticker := time.NewTicker(cfg.RetentionInterval)
+		defer ticker.Stop()
+		for {
+			s.applyRetention(ctx, cfg.RetentionMaxAge)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the retention stopped, ctx of StartRetention being done.
+func (s *ArchiveService) Wait() {
+	s.retention.Wait()
+}
+
+func (s *ArchiveService) applyRetention(ctx context.Context, maxAge time.Duration) {
+	before := time.Now().Add(-maxAge)
+	archived, err := s.archive.ArchiveStocks(ctx, before)
+	if err != nil {
+		log.Println("Retention failed:", err)
+		return
+	}
+	log.Println("Retention archived", archived, "stocks rated before", before.Format(time.RFC3339))
+}
+
+type PurgeConfirmation struct {
+	Token     string    `json:"confirm_token"`
+	Rows      int       `json:"rows"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PurgeResult struct {
+	Deleted  int      `json:"deleted"`
+	Snapshot Snapshot `json:"snapshot"`
+}
+
+// RequestPurge issues the single-use token PurgeStocks asks for, so deleting
+// every stock always takes two requests.
+func (s *ArchiveService) RequestPurge(ctx context.Context) (PurgeConfirmation, error) {
+	rows, err := s.archive.CountStocks(ctx)
+	if err != nil {
+		return PurgeConfirmation{}, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return PurgeConfirmation{}, fmt.Errorf("can't generate a confirmation token: %v", err)
+	}
+	confirmation := PurgeConfirmation{
+		Token:     hex.EncodeToString(buf),
+		Rows:      rows,
+		ExpiresAt: time.Now().Add(purgeConfirmationTTL).UTC(),
+	}
+
+	s.purgeMu.Lock()
+	defer s.purgeMu.Unlock()
+	for token, expiresAt := range s.purgeTokens {
+		if time.Now().After(expiresAt) {
+			delete(s.purgeTokens, token)
+		}
+	}
+	s.purgeTokens[confirmation.Token] = confirmation.ExpiresAt
+	return confirmation, nil
+}
+
+// PurgeStocks deletes every stored stock once token is confirmed, after
+// exporting them to a snapshot they can be restored from.
+func (s *ArchiveService) PurgeStocks(ctx context.Context, token string) (PurgeResult, error) {
+	s.purgeMu.Lock()
+	expiresAt, ok := s.purgeTokens[token]
+	delete(s.purgeTokens, token)
+	s.purgeMu.Unlock()
+	if !ok || time.Now().After(expiresAt) {
+		return PurgeResult{}, ErrInvalidConfirmation
+	}
+
+	snapshot, err := s.TakeSnapshot(ctx, "purge")
+	if err != nil {
+		return PurgeResult{}, fmt.Errorf("error taking the snapshot, nothing was deleted: %w", err)
+	}
+
+	deleted, err := s.archive.PurgeStocks(ctx)
+	if err != nil {
+		return PurgeResult{Snapshot: snapshot}, err
+	}
+	return PurgeResult{Deleted: deleted, Snapshot: snapshot}, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"backend/dto"
+	"backend/repositories"
+	"backend/xlsx"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+)
+
+// ExportContentTypes are the media types of the export formats.
+var ExportContentTypes = map[string]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportNDJSON: "application/x-ndjson",
+	ExportXLSX:   xlsx.ContentType,
+}
+
+// exportBatchSize is how many stocks are read from the database at once.
+const exportBatchSize = 1000
+
+// exportColumns are the columns of the CSV and XLSX exports, a CSV export
+// can be imported back.
+var exportColumns = []interface{}{
+	"ticker", "company", "brokerage", "action", "rating_from", "rating_to",
+	"target_from", "target_to", "target_change", "target_change_pct", "time", "source",
+}
+
+func exportRow(s dto.Stock) []interface{} {
+	return []interface{}{
+		s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo,
+		s.TargetFrom, s.TargetTo, s.TargetChange, s.TargetChangePct, s.Time, s.Source,
+	}
+}
+
+// rowWriter writes the stocks of an export in one format.
+type rowWriter interface {
+	Write(stock dto.Stock) error
+	Flush() error
+	Close() error
+}
+
+// ExportService streams the stored ratings of a StockRepository to files.
+type ExportService struct {
+	repo repositories.StockRepository
+}
+
+func NewExportService(repo repositories.StockRepository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// Export writes the stocks matching filter to w in format, newest first.
+// They are read batch by batch through the keyset cursor, so an export of
+// the whole table doesn't hold it in memory, and w is flushed after every
+// batch when it can be. It returns the number of stocks written.
+func (s *ExportService) Export(ctx context.Context, filter repositories.StockFilter, format string, w io.Writer) (int, error) {
+	rows, err := newRowWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	cursor := ""
+	for {
+		page, err := s.repo.FindCursor(ctx, filter, cursor, exportBatchSize, false)
+		if err != nil {
+			return written, fmt.Errorf("can't read stocks: %w", err)
+		}
+		for _, stock := range page.Items {
+			if err := rows.Write(dto.NewStock(stock)); err != nil {
+				return written, fmt.Errorf("can't write the export: %w", err)
+			}
+			written++
+		}
+		if err := rows.Flush(); err != nil {
+			return written, fmt.Errorf("can't write the export: %w", err)
+		}
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if err := rows.Close(); err != nil {
+		return written, fmt.Errorf("can't write the export: %w", err)
+	}
+	return written, nil
+}
+
+func newRowWriter(format string, w io.Writer) (rowWriter, error) {
+	switch format {
+	case ExportCSV:
+		c := csv.NewWriter(w)
+		if err := c.Write(csvRecord(exportColumns)); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{c}, nil
+	case ExportNDJSON:
+		return &ndjsonRowWriter{json.NewEncoder(w)}, nil
+	case ExportXLSX:
+		x, err := xlsx.NewWriter(w, "Stocks")
+		if err != nil {
+			return nil, err
+		}
+		if err := x.WriteRow(exportColumns...); err != nil {
+			return nil, err
+		}
+		return &xlsxRowWriter{x}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q, expected csv, ndjson or xlsx", format)
+	}
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) Write(stock dto.Stock) error {
+	return c.w.Write(csvRecord(exportRow(stock)))
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
+
+// csvRecord formats the cells the way the import reads them back.
+func csvRecord(cells []interface{}) []string {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case string:
+			record[i] = v
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case *float64:
+			if v != nil {
+				record[i] = strconv.FormatFloat(*v, 'f', -1, 64)
+			}
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return record
+}
+
+type ndjsonRowWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonRowWriter) Write(stock dto.Stock) error {
+	return n.enc.Encode(stock)
+}
+
+func (n *ndjsonRowWriter) Flush() error { return nil }
+func (n *ndjsonRowWriter) Close() error { return nil }
+
+type xlsxRowWriter struct {
+	w *xlsx.Writer
+}
+
+func (x *xlsxRowWriter) Write(stock dto.Stock) error {
+	return x.w.WriteRow(exportRow(stock)...)
+}
+
+func (x *xlsxRowWriter) Flush() error {
+	return x.w.Flush()
+}
+
+func (x *xlsxRowWriter) Close() error {
+	return x.w.Close()
+}
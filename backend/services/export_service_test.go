@@ -0,0 +1,140 @@
+package services
+
+import (
+	"archive/zip"
+	"backend/models"
+	"backend/repositories"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// exportedStocks are more than one batch of the export.
+func exportedStocks() []models.Stock {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stocks := make([]models.Stock, exportBatchSize+500)
+	for i := range stocks {
+		stocks[i] = models.Stock{
+			Ticker:     fmt.Sprintf("T%04d", i),
+			TargetFrom: float64(i),
+			TargetTo:   float64(i) + 0.25,
+			Company:    fmt.Sprintf(`Company "%d", Inc.`, i),
+			Action:     "upgraded by",
+			Brokerage:  "UBS",
+			RatingFrom: "Hold",
+			RatingTo:   "Buy",
+			Time:       day.Add(time.Duration(i) * time.Minute),
+			Source:     "test",
+		}
+	}
+	return stocks
+}
+
+func TestExportCSVImportsBack(t *testing.T) {
+	ctx := context.Background()
+	stocks := exportedStocks()
+	exports := NewExportService(repositories.NewMemoryStockRepository(stocks...))
+
+	var buf bytes.Buffer
+	written, err := exports.Export(ctx, repositories.StockFilter{}, ExportCSV, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if written != len(stocks) {
+		t.Errorf("got %d written, want %d", written, len(stocks))
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("can't read the export: %v", err)
+	}
+	if len(records) != len(stocks)+1 || records[0][0] != "ticker" {
+		t.Fatalf("got %d records starting with %v, want the header and %d rows", len(records), records[0], len(stocks))
+	}
+	// newest first
+	if got := records[1][0]; got != stocks[len(stocks)-1].Ticker {
+		t.Errorf("got %s first, want %s", got, stocks[len(stocks)-1].Ticker)
+	}
+
+	imported := repositories.NewMemoryStockRepository()
+	report, err := NewImportService(imported).ImportRatings(ctx, &buf, "csv", "test")
+	if err != nil {
+		t.Fatalf("can't import the export: %v", err)
+	}
+	if report.Inserted != len(stocks) || report.Failed != 0 {
+		t.Fatalf("got %+v, want %d inserted", report, len(stocks))
+	}
+	existing, err := imported.GetExisting(ctx, stocks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(existing) != len(stocks) {
+		t.Fatalf("got %d stocks back, want %d", len(existing), len(stocks))
+	}
+	exported := make(map[string]models.Stock, len(stocks))
+	for _, stock := range stocks {
+		exported[stock.Key()] = stock
+	}
+	for _, stock := range existing {
+		if fields := exported[stock.Key()].Diff(stock); len(fields) > 0 {
+			t.Errorf("%s: got %v changed by the round trip", stock.Ticker, fields)
+		}
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	stocks := exportedStocks()
+	exports := NewExportService(repositories.NewMemoryStockRepository(stocks...))
+
+	var buf bytes.Buffer
+	if _, err := exports.Export(context.Background(), repositories.StockFilter{}, ExportXLSX, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("can't open the workbook: %v", err)
+	}
+	f, err := z.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("can't open the sheet: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("can't read the sheet: %v", err)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []string `xml:"c>is>t"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		t.Fatalf("can't parse the sheet: %v", err)
+	}
+	if len(sheet.Rows) != len(stocks)+1 {
+		t.Fatalf("got %d rows, want the header and %d rows", len(sheet.Rows), len(stocks))
+	}
+	header := sheet.Rows[0].Cells
+	if len(header) != len(exportColumns) || header[0] != "ticker" {
+		t.Errorf("got header %v, want the export columns", header)
+	}
+	// the text cells of a row: ticker, company, brokerage, action, ratings and source
+	newest := stocks[len(stocks)-1]
+	if got := sheet.Rows[1].Cells; len(got) < 2 || got[0] != newest.Ticker || got[1] != newest.Company {
+		t.Errorf("got first row %v, want %s %s", got, newest.Ticker, newest.Company)
+	}
+}
+
+func TestExportRejectsUnknownFormats(t *testing.T) {
+	exports := NewExportService(repositories.NewMemoryStockRepository())
+	if _, err := exports.Export(context.Background(), repositories.StockFilter{}, "pdf", io.Discard); err == nil {
+		t.Errorf("got no error exporting to pdf")
+	}
+}
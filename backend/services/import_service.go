@@ -0,0 +1,105 @@
+package services
+
+import (
+	"backend/api"
+	"backend/config"
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const importBatchSize = 100
+
+// ErrInvalidImport is the error of a dump that can't be read, as opposed to
+// rows that can't be stored.
+var ErrInvalidImport = errors.New("invalid import file")
+
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	Source   string           `json:"source"`
+	Rows     int              `json:"rows"`
+	Inserted int              `json:"inserted"`
+	Updated  int              `json:"updated"`
+	Ignored  int              `json:"ignored"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportService imports rating dumps into the stocks repository.
+type ImportService struct {
+	stocks repositories.StockRepository
+}
+
+func NewImportService(stocks repositories.StockRepository) *ImportService {
+	return &ImportService{stocks: stocks}
+}
+
+// ImportRatings reads a CSV or NDJSON dump, validates every row like a sync
+// does with ConvertStockApi and stores the valid ones with the same batch
+// writer and conflict policy.
+// Rows that fail are reported by line and skipped.
+func (s *ImportService) ImportRatings(ctx context.Context, r io.Reader, format, source string) (ImportReport, error) {
+	report := ImportReport{Source: source, Errors: []ImportRowError{}}
+	var batch []models.Stock
+
+	policy, err := repositories.ParseConflictPolicy(config.LoadPipeline().ConflictPolicy)
+	if err != nil {
+		return report, err
+	}
+	writer := repositories.NewBatchWriter(s.stocks, importBatchSize, policy, 0)
+
+	var storeErr error
+	store := func() error {
+		result, err := writer.Write(ctx, batch)
+		report.Failed += result.Failed
+		if err != nil {
+			storeErr = fmt.Errorf("error storing rows: %w", err)
+			return storeErr
+		}
+		report.Inserted += result.Inserted
+		report.Updated += result.Updated
+		report.Ignored += result.Ignored
+		batch = batch[:0]
+		return nil
+	}
+
+	err = api.ReadRows(r, format, func(line int, item api.StockApi, err error) error {
+		report.Rows++
+		if err == nil {
+			var stock models.Stock
+			stock, err = api.ConvertStockApi(item)
+			if err == nil {
+				stock.Source = source
+				batch = append(batch, stock)
+			}
+		}
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportRowError{Line: line, Error: err.Error()})
+			return nil
+		}
+
+		if len(batch) >= importBatchSize {
+			return store()
+		}
+		return nil
+	})
+	if storeErr != nil {
+		return report, storeErr
+	}
+	if err != nil {
+		return report, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	if err := store(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"backend/repositories"
+	"context"
+	"strings"
+	"testing"
+)
+
+const header = "ticker,target_from,target_to,company,action,brokerage,rating_from,rating_to,time\n"
+
+var firstImport = header +
+	"AAPL,$100,$150,Apple Inc.,upgraded by,UBS,Hold,Buy,2025-01-01T00:00:00Z\n" +
+	"MSFT,$300,$330,Microsoft Corporation,target raised by,UBS,Buy,Buy,2025-01-01T00:00:00Z\n"
+
+// reimport corrects the AAPL target, repeats MSFT as is and adds NVDA.
+var reimport = header +
+	"AAPL,$100,$175,Apple Inc.,upgraded by,UBS,Hold,Buy,2025-01-01T00:00:00Z\n" +
+	"MSFT,$300,$330,Microsoft Corporation,target raised by,UBS,Buy,Buy,2025-01-01T00:00:00Z\n" +
+	"NVDA,$100,$140,NVIDIA Corporation,initiated by,UBS,,Buy,2025-01-01T00:00:00Z\n"
+
+func TestImportRatingsReimport(t *testing.T) {
+	tests := []struct {
+		policy    string
+		inserted  int
+		updated   int
+		ignored   int
+		stored    float64 // TargetTo of AAPL afterwards
+		revisions int
+	}{
+		{"ignore", 1, 0, 2, 150, 0},
+		{"overwrite", 1, 1, 1, 175, 0},
+		{"version", 1, 1, 1, 175, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			t.Setenv("SYNC_CONFLICT_POLICY", test.policy)
+			ctx := context.Background()
+			repos, err := repositories.NewSQLiteRepositories(":memory:")
+			if err != nil {
+				t.Fatalf("can't open the repositories: %v", err)
+			}
+			imports := NewImportService(repos.Stocks)
+
+			if _, err := imports.ImportRatings(ctx, strings.NewReader(firstImport), "csv", "import"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			report, err := imports.ImportRatings(ctx, strings.NewReader(reimport), "csv", "import")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report.Rows != 3 || report.Inserted != test.inserted || report.Updated != test.updated || report.Ignored != test.ignored {
+				t.Errorf("got %+v, want %d inserted, %d updated and %d ignored of 3 rows", report, test.inserted, test.updated, test.ignored)
+			}
+
+			_, _, _, total, err := repos.Stocks.GetAll(ctx, 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if total != 3 {
+				t.Errorf("got %d rows, want 3", total)
+			}
+			items, _, _, _, err := repos.Stocks.GetByTicker(ctx, "AAPL", 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != 1 || items[0].TargetTo != test.stored {
+				t.Errorf("got %+v, want one AAPL row with target_to %v", items, test.stored)
+			}
+
+			revisions, count, err := repos.Revisions.GetRevisions(ctx, "AAPL", 1, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != test.revisions {
+				t.Fatalf("got %d revisions, want %d", count, test.revisions)
+			}
+			if count > 0 {
+				revision := revisions[0]
+				if revision.Fields != "target_to" || revision.Previous.TargetTo != 150 || revision.New.TargetTo != 175 {
+					t.Errorf("got revision %+v, want target_to from 150 to 175", revision)
+				}
+			}
+			for _, ticker := range []string{"MSFT", "NVDA"} {
+				if _, count, _ := repos.Revisions.GetRevisions(ctx, ticker, 1, 10); count != 0 {
+					t.Errorf("got %d revisions of %s, want 0", count, ticker)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"backend/api"
+	"backend/config"
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const reprocessChunk = 100
+
+type ReprocessResult struct {
+	Processed int `json:"processed"`
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Ignored   int `json:"ignored"`
+	Failed    int `json:"failed"`
+}
+
+// QuarantineService releases the quarantined rows into the stocks repository.
+type QuarantineService struct {
+	quarantine *repositories.QuarantineRepository
+	stocks     repositories.StockRepository
+}
+
+func NewQuarantineService(quarantine *repositories.QuarantineRepository, stocks repositories.StockRepository) *QuarantineService {
+	return &QuarantineService{quarantine: quarantine, stocks: stocks}
+}
+
+// Reprocess runs the quarantined rows matching filter through
+// ConvertStockApi again. Rows that convert are stored and released from
+// quarantine, the rest keep their row with the new error.
+func (s *QuarantineService) Reprocess(ctx context.Context, filter repositories.QuarantineFilter) (ReprocessResult, error) {
+	var result ReprocessResult
+	var afterID uint
+
+	policy, err := repositories.ParseConflictPolicy(config.LoadPipeline().ConflictPolicy)
+	if err != nil {
+		return result, err
+	}
+	writer := repositories.NewBatchWriter(s.stocks, reprocessChunk, policy, 0)
+
+	for {
+		rows, err := s.quarantine.GetQuarantinedAfter(ctx, filter, afterID, reprocessChunk)
+		if err != nil {
+			return result, fmt.Errorf("error loading quarantined rows: %w", err)
+		}
+		if len(rows) == 0 {
+			return result, nil
+		}
+		afterID = rows[len(rows)-1].ID
+
+		var stocks []models.Stock
+		var released []uint
+		for _, row := range rows {
+			result.Processed++
+
+			stock, err := convertQuarantined(row)
+			if err != nil {
+				result.Failed++
+				if err := s.quarantine.UpdateQuarantinedError(ctx, row.ID, err.Error()); err != nil {
+					return result, fmt.Errorf("error updating quarantined row %d: %v", row.ID, err)
+				}
+				continue
+			}
+			stocks = append(stocks, stock)
+			released = append(released, row.ID)
+		}
+
+		stored, err := writer.Write(ctx, stocks)
+		if err != nil {
+			return result, fmt.Errorf("error storing reprocessed rows: %w", err)
+		}
+		result.Inserted += stored.Inserted
+		result.Updated += stored.Updated
+		result.Ignored += stored.Ignored
+
+		if len(released) > 0 {
+			if _, err := s.quarantine.DeleteQuarantined(ctx, repositories.QuarantineFilter{IDs: released}); err != nil {
+				return result, fmt.Errorf("error releasing quarantined rows: %w", err)
+			}
+		}
+	}
+}
+
+func convertQuarantined(row models.QuarantinedStock) (models.Stock, error) {
+	var item api.StockApi
+	if err := json.Unmarshal([]byte(row.Payload), &item); err != nil {
+		return models.Stock{}, fmt.Errorf("invalid payload: %v", err)
+	}
+	stock, err := api.ConvertStockApi(item)
+	if err != nil {
+		return models.Stock{}, err
+	}
+	stock.Source = row.Source
+	return stock, nil
+}
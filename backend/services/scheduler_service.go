@@ -0,0 +1,244 @@
+package services
+
+import (
+	"backend/config"
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type SchedulerStatus struct {
+	Enabled    bool       `json:"enabled"`
+	Paused     bool       `json:"paused"`
+	Schedule   string     `json:"schedule,omitempty"`
+	Jitter     string     `json:"jitter,omitempty"`
+	QuietHours string     `json:"quiet_hours,omitempty"`
+	NextRun    *time.Time `json:"next_run"`
+	LastRun    *time.Time `json:"last_run"`
+	LastJobID  uint       `json:"last_job_id,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// Scheduler triggers the same sync as /api/sync on an interval or a cron
+// expression, skipping quiet hours.
+type Scheduler struct {
+	mu       sync.Mutex
+	schedule cron.Schedule
+	jitter   time.Duration
+	quiet    *quietHours
+	status   SchedulerStatus
+	wake     chan struct{}
+	syncs    *SyncService
+	wg       sync.WaitGroup
+}
+
+// NewScheduler returns nil when neither an interval nor a cron expression is
+// configured.
+func NewScheduler(cfg config.ScheduleConfig, syncs *SyncService) (*Scheduler, error) {
+	s := &Scheduler{wake: make(chan struct{}, 1), syncs: syncs}
+
+	switch {
+	case cfg.Cron != "":
+		schedule, err := cron.ParseStandard(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SYNC_CRON %q: %v", cfg.Cron, err)
+		}
+		s.schedule = schedule
+		s.status.Schedule = "cron " + cfg.Cron
+	case cfg.Interval > 0:
+		s.schedule = cron.Every(cfg.Interval)
+		s.status.Schedule = "every " + cfg.Interval.String()
+	default:
+		return nil, nil
+	}
+
+	if cfg.QuietHours != "" {
+		quiet, err := parseQuietHours(cfg.QuietHours)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SYNC_QUIET_HOURS %q: %v", cfg.QuietHours, err)
+		}
+		s.quiet = &quiet
+		s.status.QuietHours = cfg.QuietHours
+	}
+
+	if cfg.Jitter > 0 {
+		s.jitter = cfg.Jitter
+		s.status.Jitter = cfg.Jitter.String()
+	}
+	s.status.Enabled = true
+	s.scheduleNext(time.Now())
+	return s, nil
+}
+
+// StartScheduler builds the scheduler from the configuration and runs it
+// until ctx is done. It returns nil when the scheduler is disabled.
+func StartScheduler(ctx context.Context, cfg config.ScheduleConfig, syncs *SyncService) (*Scheduler, error) {
+	s, err := NewScheduler(cfg, syncs)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		log.Println("Sync scheduler disabled, set SYNC_INTERVAL or SYNC_CRON to enable it")
+		return nil, nil
+	}
+
+	log.Println("Sync scheduler running", s.status.Schedule, "next run at", s.status.NextRun)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+	return s, nil
+}
+
+// Wait blocks until the scheduler stopped, ctx of StartScheduler being done.
+func (s *Scheduler) Wait() {
+	if s == nil {
+		return
+	}
+	s.wg.Wait()
+}
+
+// Status is the zero SchedulerStatus on a nil, disabled, scheduler.
+func (s *Scheduler) Status() SchedulerStatus {
+	if s == nil {
+		return SchedulerStatus{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	s.status.Paused = true
+	s.status.NextRun = nil
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	if s.status.Paused {
+		s.status.Paused = false
+		s.scheduleNext(time.Now())
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if !s.status.Paused && s.status.NextRun != nil {
+			timer = time.NewTimer(time.Until(*s.status.NextRun))
+			fire = timer.C
+		}
+		s.mu.Unlock()
+
+		fired := false
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-fire:
+			fired = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !fired {
+			continue
+		}
+
+		now := time.Now()
+		job, created, err := s.syncs.StartSync(false)
+
+		s.mu.Lock()
+		s.status.LastRun = &now
+		s.status.LastError = ""
+		if err != nil {
+			s.status.LastError = err.Error()
+			log.Println("Scheduled sync failed to start:", err)
+		} else {
+			s.status.LastJobID = job.ID
+			if !created {
+				log.Println("Scheduled sync attached to running job", job.ID)
+			}
+		}
+		if !s.status.Paused {
+			s.scheduleNext(now)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// scheduleNext must be called with s.mu held.
+func (s *Scheduler) scheduleNext(from time.Time) {
+	next := s.schedule.Next(from)
+	if s.jitter > 0 {
+		next = next.Add(rand.N(s.jitter))
+	}
+	if s.quiet != nil && s.quiet.contains(next) {
+		next = s.quiet.end(next)
+	}
+	s.status.NextRun = &next
+}
+
+// quietHours is a daily window in local time, it may wrap around midnight.
+type quietHours struct {
+	from time.Duration
+	to   time.Duration
+}
+
+func parseQuietHours(value string) (quietHours, error) {
+	var startH, startM, endH, endM int
+	if _, err := fmt.Sscanf(value, "%d:%d-%d:%d", &startH, &startM, &endH, &endM); err != nil {
+		return quietHours{}, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	if startH > 23 || endH > 23 || startM > 59 || endM > 59 || startH < 0 || endH < 0 || startM < 0 || endM < 0 {
+		return quietHours{}, fmt.Errorf("hours must be between 00:00 and 23:59")
+	}
+	return quietHours{
+		from: time.Duration(startH)*time.Hour + time.Duration(startM)*time.Minute,
+		to:   time.Duration(endH)*time.Hour + time.Duration(endM)*time.Minute,
+	}, nil
+}
+
+func (q quietHours) contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+	if q.from <= q.to {
+		return offset >= q.from && offset < q.to
+	}
+	return offset >= q.from || offset < q.to
+}
+
+// end returns the moment the quiet window containing t is over.
+func (q quietHours) end(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := midnight.Add(q.to)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotBatchSize = 500
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is an NDJSON export of the stocks table, one models.Stock per
+// line, taken before destructive operations.
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Rows      int       `json:"rows,omitempty"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RestoreResult struct {
+	Rows     int `json:"rows"`
+	Inserted int `json:"inserted"`
+	Ignored  int `json:"ignored"`
+}
+
+// TakeSnapshot exports every stored stock to a new file of the snapshot
+// directory, reason ends up in its name.
+func (s *ArchiveService) TakeSnapshot(ctx context.Context, reason string) (Snapshot, error) {
+	dir := s.cfg.SnapshotDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Snapshot{}, fmt.Errorf("can't create the snapshot directory: %v", err)
+	}
+
+	createdAt := time.Now().UTC()
+	snapshot := Snapshot{
+		Name:      fmt.Sprintf("stocks-%s-%s.ndjson", createdAt.Format("20060102T150405.000Z"), reason),
+		CreatedAt: createdAt,
+	}
+
+	// written under a temporary name so a failed export never looks complete
+	file, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("can't create the snapshot file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	err = s.archive.EachStock(ctx, snapshotBatchSize, func(stocks []models.Stock) error {
+		for _, stock := range stocks {
+			if err := encoder.Encode(stock); err != nil {
+				return fmt.Errorf("can't write the snapshot: %v", err)
+			}
+			snapshot.Rows++
+		}
+		return nil
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := file.Close(); err != nil {
+		return Snapshot{}, fmt.Errorf("can't write the snapshot: %v", err)
+	}
+
+	path := filepath.Join(dir, snapshot.Name)
+	if err := os.Rename(file.Name(), path); err != nil {
+		return Snapshot{}, fmt.Errorf("can't save the snapshot: %v", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		snapshot.Size = info.Size()
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots returns the snapshots of the snapshot directory, newest first.
+func (s *ArchiveService) ListSnapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.cfg.SnapshotDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read the snapshot directory: %v", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() || !validSnapshotName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime().UTC(),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name > snapshots[j].Name
+	})
+	return snapshots, nil
+}
+
+// RestoreSnapshot stores the stocks of the snapshot name again. Rows stored
+// since the snapshot was taken are kept as they are.
+func (s *ArchiveService) RestoreSnapshot(ctx context.Context, name string) (RestoreResult, error) {
+	var result RestoreResult
+	if !validSnapshotName(name) {
+		return result, ErrSnapshotNotFound
+	}
+
+	file, err := os.Open(filepath.Join(s.cfg.SnapshotDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return result, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return result, fmt.Errorf("can't open the snapshot: %v", err)
+	}
+	defer file.Close()
+
+	writer := repositories.NewBatchWriter(s.stocks, snapshotBatchSize, repositories.ConflictIgnore, 0)
+	write := func(stocks []models.Stock) error {
+		stored, err := writer.Write(ctx, stocks)
+		result.Inserted += stored.Inserted
+		result.Ignored += stored.Ignored
+		return err
+	}
+
+	decoder := json.NewDecoder(file)
+	batch := make([]models.Stock, 0, snapshotBatchSize)
+	for {
+		var stock models.Stock
+		err := decoder.Decode(&stock)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("can't read the snapshot at row %d: %v", result.Rows+1, err)
+		}
+		result.Rows++
+
+		batch = append(batch, stock)
+		if len(batch) == snapshotBatchSize {
+			if err := write(batch); err != nil {
+				return result, err
+			}
+			batch = batch[:0]
+		}
+	}
+	return result, write(batch)
+}
+
+// validSnapshotName keeps names to plain files of the snapshot directory.
+func validSnapshotName(name string) bool {
+	return strings.HasPrefix(name, "stocks-") &&
+		strings.HasSuffix(name, ".ndjson") &&
+		filepath.Base(name) == name
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"backend/models"
 	"backend/repositories"
+	"context"
 	"fmt"
 	"log"
 	"sort"
@@ -36,15 +37,24 @@ func isUpgrade(from, to string) bool {
 	return isPositiveRating(to) && !isPositiveRating(from)
 }
 
-func GetRecommendationsService() ([]models.Recommendation, error) {
+// RecommendationService scores the stored ratings of a StockRepository.
+type RecommendationService struct {
+	repo repositories.StockRepository
+}
+
+func NewRecommendationService(repo repositories.StockRepository) *RecommendationService {
+	return &RecommendationService{repo: repo}
+}
+
+func (s *RecommendationService) GetRecommendations(ctx context.Context) ([]models.Recommendation, error) {
 
 	scores := make(map[string]*models.Recommendation)
 	reasons := make(map[string][]string) // Store reasons per ticker
 
-	stocks, err := repositories.GetByRecommendation()
+	stocks, err := s.repo.GetByRecommendation(ctx)
 	if err != nil {
 		log.Println("Error fetching recommendations:", err)
-		return nil, fmt.Errorf("error fetching recommendations: %v", err)
+		return nil, fmt.Errorf("error fetching recommendations: %w", err)
 	}
 
 	for _, stock := range stocks {
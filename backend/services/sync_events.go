@@ -0,0 +1,44 @@
+package services
+
+import (
+	"backend/api"
+	"sync"
+)
+
+// subscribers get events on a buffered channel. A subscriber that falls that
+// far behind misses events rather than stalling the sync.
+const subscriberBuffer = 64
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = map[chan api.SyncEvent]struct{}{}
+)
+
+// SubscribeSyncEvents returns a channel receiving the events of every sync
+// and a function to stop receiving them.
+func SubscribeSyncEvents() (<-chan api.SyncEvent, func()) {
+	events := make(chan api.SyncEvent, subscriberBuffer)
+
+	subscribersMu.Lock()
+	subscribers[events] = struct{}{}
+	subscribersMu.Unlock()
+
+	unsubscribe := func() {
+		subscribersMu.Lock()
+		delete(subscribers, events)
+		subscribersMu.Unlock()
+	}
+	return events, unsubscribe
+}
+
+func publishSyncEvent(event api.SyncEvent) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for events := range subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"backend/api"
+	"backend/models"
+	"backend/repositories"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// syncRun is the in-memory side of the job currently crawling the upstream.
+// Its progress is persisted after every page, but reads of the running job
+// are served from here so they don't lag behind.
+type syncRun struct {
+	mu   sync.Mutex
+	job  models.SyncJob
+	done chan struct{}
+}
+
+func (s *syncRun) snapshot() models.SyncJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.job
+	if job.StartedAt != nil && job.FinishedAt == nil {
+		job.DurationMs = time.Since(*job.StartedAt).Milliseconds()
+	}
+	return job
+}
+
+const progressInterval = time.Second
+
+// SyncService runs the syncs into its repositories as background jobs, one
+// at a time.
+type SyncService struct {
+	ctx   context.Context
+	repos *repositories.Repositories
+
+	mu         sync.Mutex
+	currentRun *syncRun
+}
+
+// NewSyncService runs the syncs under ctx, the lifetime of the server rather
+// than of the request that started them: once ctx is done the running sync
+// stops and its job is recorded as failed.
+func NewSyncService(ctx context.Context, repos *repositories.Repositories) *SyncService {
+	return &SyncService{ctx: ctx, repos: repos}
+}
+
+func (s *SyncService) current() *syncRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentRun
+}
+
+// StartSync enqueues a sync job and runs it in the background. Only one sync
+// runs at a time: if one is already queued or running it is returned instead
+// and created is false.
+func (s *SyncService) StartSync(restart bool) (job models.SyncJob, created bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.currentRun != nil {
+		return s.currentRun.snapshot(), false, nil
+	}
+	if err := s.ctx.Err(); err != nil {
+		return models.SyncJob{}, false, fmt.Errorf("can't enqueue sync job, shutting down: %w", err)
+	}
+
+	job = models.SyncJob{State: models.SyncJobQueued, Restart: restart}
+	if err := s.repos.Sync.CreateSyncJob(s.ctx, &job); err != nil {
+		return models.SyncJob{}, false, fmt.Errorf("can't enqueue sync job: %w", err)
+	}
+
+	run := &syncRun{job: job, done: make(chan struct{})}
+	s.currentRun = run
+	go s.runSync(s.ctx, run)
+
+	return job, true, nil
+}
+
+// WaitSync blocks until the job finishes, or ctx is done, and returns its
+// state.
+func (s *SyncService) WaitSync(ctx context.Context, id uint) (models.SyncJob, error) {
+	if run := s.current(); run != nil && run.job.ID == id {
+		select {
+		case <-run.done:
+		case <-ctx.Done():
+			return models.SyncJob{}, ctx.Err()
+		}
+	}
+	return s.repos.Sync.GetSyncJob(ctx, id)
+}
+
+func (s *SyncService) GetSyncJob(ctx context.Context, id uint) (models.SyncJob, error) {
+	if run := s.current(); run != nil && run.job.ID == id {
+		return run.snapshot(), nil
+	}
+	return s.repos.Sync.GetSyncJob(ctx, id)
+}
+
+func (s *SyncService) ListSyncJobs(ctx context.Context, limit int) ([]models.SyncJob, error) {
+	jobs, err := s.repos.Sync.ListSyncJobs(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if run := s.current(); run != nil {
+		for i := range jobs {
+			if jobs[i].ID == run.job.ID {
+				jobs[i] = run.snapshot()
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// Wait blocks until the running sync, if any, is done. Once the context of
+// the service is done no other sync starts.
+func (s *SyncService) Wait() {
+	if run := s.current(); run != nil {
+		<-run.done
+	}
+}
+
+// CurrentSyncJob returns the job currently queued or running, if any.
+func (s *SyncService) CurrentSyncJob() (job models.SyncJob, ok bool) {
+	run := s.current()
+	if run == nil {
+		return models.SyncJob{}, false
+	}
+	return run.snapshot(), true
+}
+
+func (s *SyncService) runSync(ctx context.Context, run *syncRun) {
+	defer func() {
+		s.mu.Lock()
+		s.currentRun = nil
+		s.mu.Unlock()
+		close(run.done)
+	}()
+	// the job is still recorded once ctx is done
+	bookkeeping := context.WithoutCancel(ctx)
+
+	run.mu.Lock()
+	started := time.Now()
+	run.job.State = models.SyncJobRunning
+	run.job.StartedAt = &started
+	restart := run.job.Restart
+	job := run.job
+	run.mu.Unlock()
+	if err := s.repos.Sync.SaveSyncJob(bookkeeping, job); err != nil {
+		log.Println("can't mark sync job as running:", err)
+	}
+
+	// progress is persisted at most once per progressInterval, reads of the
+	// running job are served from memory anyway
+	var lastSave time.Time
+	stats, err := api.FetchData(ctx, s.repos, api.SyncOptions{JobID: job.ID, Restart: restart, OnEvent: func(event api.SyncEvent) {
+		event.JobID = job.ID
+		publishSyncEvent(event)
+
+		// events come from the stages concurrently
+		run.mu.Lock()
+		applyStats(&run.job, event.Totals)
+		job := run.job
+		save := time.Since(lastSave) >= progressInterval
+		if save {
+			lastSave = time.Now()
+		}
+		run.mu.Unlock()
+		if !save {
+			return
+		}
+		if err := s.repos.Sync.SaveSyncJob(bookkeeping, job); err != nil {
+			log.Println("can't save sync job progress:", err)
+		}
+	}})
+
+	run.mu.Lock()
+	finished := time.Now()
+	applyStats(&run.job, stats)
+	run.job.FinishedAt = &finished
+	run.job.DurationMs = finished.Sub(started).Milliseconds()
+	if err != nil {
+		run.job.State = models.SyncJobFailed
+		run.job.Error = err.Error()
+		if ctx.Err() != nil {
+			run.job.Error = "interrupted by shutdown: " + run.job.Error
+		}
+	} else {
+		run.job.State = models.SyncJobSucceeded
+	}
+	job = run.job
+	run.mu.Unlock()
+
+	if err := s.repos.Sync.SaveSyncJob(bookkeeping, job); err != nil {
+		log.Println("can't save finished sync job:", err)
+	}
+
+	event := api.SyncEvent{Type: api.EventCompleted, JobID: job.ID, Totals: stats, Time: finished}
+	if err != nil {
+		event.Type = api.EventFailed
+		event.Error = err.Error()
+	}
+	publishSyncEvent(event)
+
+	log.Printf("sync job %d %s: %d pages, %d inserted, %d updated, %d ignored, %d failed, %d rejected (fetch %dms, convert %dms, write %dms, stalled %dms)",
+		job.ID, job.State, job.PagesFetched, job.Inserted, job.Updated, job.Ignored, job.Failed, job.Rejected,
+		job.FetchMs, job.ConvertMs, job.WriteMs, job.StalledMs)
+}
+
+func applyStats(job *models.SyncJob, stats api.SyncStats) {
+	job.PagesFetched = stats.Pages
+	job.Inserted = stats.Inserted
+	job.Updated = stats.Updated
+	job.Ignored = stats.Ignored
+	job.Rejected = stats.Rejected
+	job.Failed = stats.Failed
+	job.FetchMs = stats.Timings.FetchMs
+	job.ConvertMs = stats.Timings.ConvertMs
+	job.WriteMs = stats.Timings.WriteMs
+	job.StalledMs = stats.Timings.StalledMs
+}
+
+// DryRunSync previews a full sync without writing anything. It doesn't go
+// through the job queue since it has nothing to serialize against.
+func (s *SyncService) DryRunSync(ctx context.Context) (api.DiffReport, error) {
+	return api.DryRun(ctx, s.repos.Stocks, nil)
+}
@@ -0,0 +1,205 @@
+// Package xlsx writes single sheet Excel workbooks as a stream, row after
+// row, without holding the sheet in memory.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// the parts of the workbook besides the sheet, which is streamed
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+	relsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+	// cell styles: 0 default, 1 date and time, 2 bold for the header
+	stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+	sheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+<sheetData>`
+
+	sheetEnd = `</sheetData>
+</worksheet>`
+)
+
+const (
+	styleDate   = 1
+	styleHeader = 2
+)
+
+// epoch is day 0 of the dates of a workbook, with the 1900 leap year bug
+// already accounted for.
+var epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Writer writes a workbook of one sheet. The first row written is the
+// header, in bold and frozen at the top. Close must be called to get a
+// valid file.
+type Writer struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewWriter starts a workbook on w with a sheet named sheetName.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	z := zip.NewWriter(w)
+
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", relsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, name.String())},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	}
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("can't write %s: %v", part.name, err)
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, fmt.Errorf("can't write %s: %v", part.name, err)
+		}
+	}
+
+	// the sheet is the last part, so it stays open while the rows come
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("can't write the sheet: %v", err)
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(sheetStart); err != nil {
+		return nil, fmt.Errorf("can't write the sheet: %v", err)
+	}
+	return &Writer{zip: z, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Cells can be strings, numbers, booleans, times or
+// nil for an empty cell, a nil pointer being empty too.
+func (w *Writer) WriteRow(cells ...interface{}) error {
+	w.rows++
+	header := w.rows == 1
+
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows)
+	for i, value := range cells {
+		ref := column(i) + strconv.Itoa(w.rows)
+		if err := w.writeCell(ref, value, header); err != nil {
+			return err
+		}
+	}
+	_, err := w.sheet.WriteString("</row>")
+	return err
+}
+
+func (w *Writer) writeCell(ref string, value interface{}, header bool) error {
+	style := ""
+	if header {
+		style = fmt.Sprintf(` s="%d"`, styleHeader)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return w.writeCell(ref, *v, header)
+	case string:
+		fmt.Fprintf(w.sheet, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+		xml.EscapeText(w.sheet, []byte(v))
+		_, err := w.sheet.WriteString("</t></is></c>")
+		return err
+	case float64:
+		_, err := fmt.Fprintf(w.sheet, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+		return err
+	case int:
+		_, err := fmt.Fprintf(w.sheet, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+		return err
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		_, err := fmt.Fprintf(w.sheet, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, style, b)
+		return err
+	case time.Time:
+		// a serial number of days, workbooks have no time zones
+		days := float64(v.UTC().Sub(epoch)) / float64(24*time.Hour)
+		_, err := fmt.Fprintf(w.sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDate, strconv.FormatFloat(days, 'f', -1, 64))
+		return err
+	default:
+		return w.writeCell(ref, fmt.Sprint(v), header)
+	}
+}
+
+// Flush pushes the rows written so far to the underlying writer.
+func (w *Writer) Flush() error {
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Flush()
+}
+
+// Close ends the sheet and the workbook, it doesn't close the underlying
+// writer.
+func (w *Writer) Close() error {
+	if _, err := w.sheet.WriteString(sheetEnd); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// column is the letter of the column at index i: A, B, ..., Z, AA, ...
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
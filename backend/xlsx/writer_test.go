@@ -0,0 +1,133 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestColumn(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"}, {25, "Z"}, {26, "AA"}, {27, "AB"}, {51, "AZ"}, {52, "BA"}, {701, "ZZ"}, {702, "AAA"},
+	}
+	for _, test := range tests {
+		if got := column(test.index); got != test.want {
+			t.Errorf("column(%d): got %s, want %s", test.index, got, test.want)
+		}
+	}
+}
+
+type sheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			S      string `xml:"s,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readPart returns the content of the part called name in the workbook.
+func readPart(t *testing.T, workbook []byte, name string) []byte {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatalf("can't open the workbook: %v", err)
+	}
+	f, err := z.Open(name)
+	if err != nil {
+		t.Fatalf("can't open %s: %v", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("can't read %s: %v", name, err)
+	}
+	return data
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Stocks & <Co>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 28 columns so the last ones are past Z
+	header := make([]interface{}, 28)
+	for i := range header {
+		header[i] = "col"
+	}
+	header[27] = `a<b & "c"`
+	if err := w.WriteRow(header...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var missing *float64
+	at := time.Date(2025, 1, 1, 18, 0, 0, 0, time.FixedZone("UTC-6", -6*3600))
+	if err := w.WriteRow("AT&T", 1.5, missing, nil, at, true, 7); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	workbook := readPart(t, buf.Bytes(), "xl/workbook.xml")
+	var book struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(workbook, &book); err != nil {
+		t.Fatalf("can't parse the workbook: %v", err)
+	}
+	if len(book.Sheets) != 1 || book.Sheets[0].Name != "Stocks & <Co>" {
+		t.Errorf("got sheets %+v, want one named Stocks & <Co>", book.Sheets)
+	}
+
+	var s sheet
+	if err := xml.Unmarshal(readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml"), &s); err != nil {
+		t.Fatalf("can't parse the sheet: %v", err)
+	}
+	if len(s.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(s.Rows))
+	}
+
+	head := s.Rows[0].Cells
+	if len(head) != 28 {
+		t.Fatalf("got %d header cells, want 28", len(head))
+	}
+	if last := head[27]; last.R != "AB1" || last.Inline != `a<b & "c"` || last.S != "2" {
+		t.Errorf("got last header cell %+v, want AB1 in bold holding a<b & \"c\"", last)
+	}
+
+	row := s.Rows[1].Cells
+	want := []struct{ ref, typ, style, value string }{
+		{"A2", "inlineStr", "", "AT&T"},
+		{"B2", "", "", "1.5"},
+		// C2 and D2 are empty and left out
+		{"E2", "", "1", "45659"}, // 2025-01-02 00:00 UTC
+		{"F2", "b", "", "1"},
+		{"G2", "", "", "7"},
+	}
+	if len(row) != len(want) {
+		t.Fatalf("got %d cells, want %d: %+v", len(row), len(want), row)
+	}
+	for i, cell := range row {
+		value := cell.V
+		if cell.T == "inlineStr" {
+			value = cell.Inline
+		}
+		if cell.R != want[i].ref || cell.T != want[i].typ || cell.S != want[i].style || value != want[i].value {
+			t.Errorf("got cell %+v, want %+v", cell, want[i])
+		}
+	}
+}
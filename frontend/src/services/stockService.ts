@@ -49,10 +49,10 @@ export async function searchStocks(type: string, query: string, page: number, pa
         baseUrl = `${API_URL}/stocks/action/${encodeURIComponent(query)}`;
         break;
       case 'ratingTo':
-        baseUrl = `${API_URL}/stocks/ratingto/${encodeURIComponent(query)}`;
+        baseUrl = `${API_URL}/stocks/rating-to/${encodeURIComponent(query)}`;
         break;
       case 'ratingFrom':
-        baseUrl = `${API_URL}/stocks/ratingfrom/${encodeURIComponent(query)}`;
+        baseUrl = `${API_URL}/stocks/rating-from/${encodeURIComponent(query)}`;
         break;
       case 'brokerage':
         baseUrl = `${API_URL}/stocks/brokerage/${encodeURIComponent(query)}`;